@@ -0,0 +1,88 @@
+package snowflake
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// A NodeAssigner hands out node IDs, e.g. from the environment, a
+// coordination service or static configuration. Assign returns the node
+// ID, the number of node bits it was allocated from, and a function that
+// gives the ID back once the node is closed.
+type NodeAssigner interface {
+	Assign() (id int, bits uint8, release func())
+}
+
+// StaticAssigner always assigns ID with Bits node bits.
+type StaticAssigner struct {
+	ID   int
+	Bits uint8
+}
+
+func (a StaticAssigner) Assign() (int, uint8, func()) {
+	return a.ID, a.Bits, func() {}
+}
+
+// NewSnowflakeNodeAssigned returns a node using the ID and node width
+// handed out by a. The assignment is released when the node is closed,
+// or immediately if the assignment cannot be used.
+func NewSnowflakeNodeAssigned(a NodeAssigner) (*SnowflakeNode, error) {
+	id, bits, release := a.Assign()
+	node, err := NewSnowflakeNodeWithBits(id, bits, baseSeqIdBits)
+	if err != nil {
+		if release != nil {
+			release()
+		}
+		return nil, err
+	}
+	node.release = release
+	return node, nil
+}
+
+// Close releases the node's ID assignment, if it has one. The node must
+// not generate IDs afterwards, since its ID may be handed to another
+// node.
+func (self *SnowflakeNode) Close() error {
+	self.mutex.Lock()
+	release := self.release
+	self.release = nil
+	self.closed = true
+	self.mutex.Unlock()
+
+	if release != nil {
+		release()
+	}
+	return nil
+}
+
+// NodeIDFromPodName returns the ordinal at the end of a Kubernetes
+// StatefulSet pod name, e.g. 3 for "app-3", for use as a node ID. It
+// fails if name has no ordinal suffix, or with ErrNodeIDOutOfRange if the
+// ordinal does not fit the default node field.
+func NodeIDFromPodName(name string) (int, error) {
+	i := strings.LastIndexByte(name, '-')
+	if i < 0 {
+		return 0, fmt.Errorf("snowflake: pod name %q has no ordinal suffix", name)
+	}
+	id, err := strconv.Atoi(name[i+1:])
+	if err != nil || name[i+1] == '+' || name[i+1] == '-' {
+		return 0, fmt.Errorf("snowflake: pod name %q has no ordinal suffix", name)
+	}
+	if id >= 1<<baseNodeBits {
+		return 0, fmt.Errorf("%w: pod ordinal %d", ErrNodeIDOutOfRange, id)
+	}
+	return id, nil
+}
+
+// NewSnowflakeNodeFromPod returns a node whose node ID is the
+// StatefulSet ordinal of the pod it runs in, parsed from the HOSTNAME
+// environment variable by NodeIDFromPodName.
+func NewSnowflakeNodeFromPod() (*SnowflakeNode, error) {
+	id, err := NodeIDFromPodName(os.Getenv("HOSTNAME"))
+	if err != nil {
+		return nil, err
+	}
+	return New(id)
+}
@@ -0,0 +1,59 @@
+package snowflake
+
+import (
+	"errors"
+	"testing"
+)
+
+type countingAssigner struct {
+	id       int
+	bits     uint8
+	released int
+}
+
+func (a *countingAssigner) Assign() (int, uint8, func()) {
+	return a.id, a.bits, func() { a.released++ }
+}
+
+func TestNewSnowflakeNodeAssigned(t *testing.T) {
+	node, err := NewSnowflakeNodeAssigned(StaticAssigner{ID: 12, Bits: 6})
+	if err != nil {
+		t.Fatalf("(1) Could not create node: %v", err)
+	}
+	if _, nodeId, _ := node.Next().Decompose(node.Info()); nodeId != 12 {
+		t.Errorf("(2) Assigned node generated node ID %d, expected 12!", nodeId)
+	}
+
+	a := &countingAssigner{id: 3, bits: 4}
+	node, _ = NewSnowflakeNodeAssigned(a)
+	node.Close()
+	node.Close()
+	if a.released != 1 {
+		t.Errorf("(3) Assignment released %d times, expected once!", a.released)
+	}
+
+	a = &countingAssigner{id: 16, bits: 4}
+	if _, err := NewSnowflakeNodeAssigned(a); err == nil || a.released != 1 {
+		t.Errorf("(4) Unusable assignment returned %v and was released %d times!", err, a.released)
+	}
+}
+
+func TestNodeIDFromPodName(t *testing.T) {
+	if id, err := NodeIDFromPodName("snowflake-api-3"); err != nil || id != 3 {
+		t.Errorf("(1) Expected ordinal 3, got %d, %v!", id, err)
+	}
+	for _, name := range []string{"", "app", "app-", "app-x", "app-+1", "app-1.5"} {
+		if _, err := NodeIDFromPodName(name); err == nil {
+			t.Errorf("(2) Pod name %q without an ordinal was not rejected!", name)
+		}
+	}
+	if _, err := NodeIDFromPodName("app-1024"); !errors.Is(err, ErrNodeIDOutOfRange) {
+		t.Errorf("(3) Out of range ordinal returned %v!", err)
+	}
+
+	t.Setenv("HOSTNAME", "app-7")
+	node, err := NewSnowflakeNodeFromPod()
+	if err != nil || node.NodeID() != 7 {
+		t.Errorf("(4) Expected a node with ID 7, got %v, %v!", node, err)
+	}
+}
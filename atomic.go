@@ -0,0 +1,61 @@
+package snowflake
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AtomicSnowflakeNode generates the same IDs as a default SnowflakeNode,
+// but claims sequence numbers with a compare-and-swap on a packed
+// (millisecond, sequence) word instead of taking a mutex. The mutex is
+// only used to park callers while they wait for the next millisecond
+// once the sequence is exhausted.
+type AtomicSnowflakeNode struct {
+	state  int64 // time << baseSeqIdBits | sequence of the last ID
+	nodeId int64
+	epoch  time.Time
+	mutex  sync.Mutex
+}
+
+func NewAtomicSnowflakeNode(shardId int) *AtomicSnowflakeNode {
+	curTime := time.Now()
+	return &AtomicSnowflakeNode{
+		nodeId: int64(shardId),
+		epoch:  curTime.Add(time.Unix(baseEpoch/1000, (baseEpoch%1000)*1000000).Sub(curTime)),
+	}
+}
+
+func (self *AtomicSnowflakeNode) millis() int64 {
+	return time.Since(self.epoch).Nanoseconds() / 1000000
+}
+
+func (self *AtomicSnowflakeNode) Next() Snowflake {
+	const seqMask = 1<<baseSeqIdBits - 1
+	for {
+		old := atomic.LoadInt64(&self.state)
+		last, seq := old>>baseSeqIdBits, old&seqMask
+		now := self.millis()
+
+		var next int64
+		switch {
+		case now > last:
+			next = now << baseSeqIdBits
+		case seq < seqMask:
+			// Same millisecond (or the clock stepped back, in which case
+			// we keep counting in the last one) -- claim the next number
+			next = old + 1
+		default:
+			// Exhausted -- wait for the clock, one waiter at a time
+			self.mutex.Lock()
+			for self.millis() <= last {
+				SpinYield.pause()
+			}
+			self.mutex.Unlock()
+			continue
+		}
+		if atomic.CompareAndSwapInt64(&self.state, old, next) {
+			return Snowflake(next>>baseSeqIdBits<<baseTimeShift | self.nodeId<<baseSeqIdBits | next&seqMask)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package snowflake
+
+import (
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestAtomicSnowflakeNode(t *testing.T) {
+	node := NewAtomicSnowflakeNode(9)
+	const goroutines, perGoroutine = 16, 5000
+
+	var mu sync.Mutex
+	var all []Snowflake
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ids := make([]Snowflake, perGoroutine)
+			for i := range ids {
+				ids[i] = node.Next()
+				if i > 0 && ids[i] <= ids[i-1] {
+					t.Errorf("(1) ID %d is not after %d!", ids[i], ids[i-1])
+				}
+			}
+			mu.Lock()
+			all = append(all, ids...)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(all, func(a, b int) bool { return all[a] < all[b] })
+	for i := range all {
+		if all[i].Node() != 9 {
+			t.Fatalf("(2) ID %d has node %d!", all[i], all[i].Node())
+		}
+		if i > 0 && all[i] == all[i-1] {
+			t.Fatalf("(3) Duplicate ID %d!", all[i])
+		}
+	}
+}
+
+// Run with -cpu 8 to compare the nodes at eight goroutines. Both use the
+// real clock, so once generation outpaces 4096 IDs per millisecond both
+// are bound by waiting for the clock rather than by locking.
+func BenchmarkSnowflakeNodeParallel(b *testing.B) {
+	node := NewSnowflakeNode(1)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			node.Next()
+		}
+	})
+}
+
+func BenchmarkAtomicSnowflakeNodeParallel(b *testing.B) {
+	node := NewAtomicSnowflakeNode(1)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			node.Next()
+		}
+	})
+}
@@ -0,0 +1,199 @@
+package snowflake
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrTimestampInUse is returned when back-dating an ID into a millisecond
+// whose sequence numbers the node may already have used.
+var ErrTimestampInUse = errors.New("snowflake: millisecond already used for live IDs")
+
+// NextN returns n new IDs, taking the node's lock once for the whole
+// batch. If the node gives up waiting for the clock (see
+// WithRetryOnExhaustion) the IDs generated so far are returned. A
+// count below one gives an empty batch.
+func (self *SnowflakeNode) NextN(n int) []Snowflake {
+	if n < 0 {
+		n = 0
+	}
+	ids := make([]Snowflake, n)
+	return ids[:self.fill(ids)]
+}
+
+// fill generates len(ids) IDs into ids and returns how many it managed.
+func (self *SnowflakeNode) fill(ids []Snowflake) int {
+	self.mutex.Lock()
+	n := self.fillLocked(ids)
+	waits := self.takeWaits()
+	self.mutex.Unlock()
+	self.reportWaits(waits)
+	return n
+}
+
+func (self *SnowflakeNode) fillLocked(ids []Snowflake) int {
+	for i := range ids {
+		now, err := self.advance(true)
+		if err != nil {
+			return i
+		}
+		ids[i] = self.compose(now, self.sequence)
+	}
+	return len(ids)
+}
+
+// BatchPool recycles SnowflakeBatch buffers so that batch generation in
+// a hot loop does not allocate. The zero value is ready to use.
+type BatchPool struct {
+	pool sync.Pool
+}
+
+// SnowflakeBatch is a batch of IDs borrowed from a BatchPool. It must not
+// be used after Release.
+type SnowflakeBatch struct {
+	ids  []Snowflake
+	pool *BatchPool
+}
+
+func (b *SnowflakeBatch) Len() int {
+	return len(b.ids)
+}
+
+func (b *SnowflakeBatch) Get(i int) Snowflake {
+	return b.ids[i]
+}
+
+// Release returns the batch to its pool.
+func (b *SnowflakeBatch) Release() {
+	b.ids = b.ids[:0]
+	b.pool.pool.Put(b)
+}
+
+// NextBatchPooled fills a batch from p with count new IDs. Call Release
+// on the batch once its IDs have been consumed. As with NextN, a count
+// below one gives an empty batch.
+func (self *SnowflakeNode) NextBatchPooled(p *BatchPool, count int) *SnowflakeBatch {
+	if count < 0 {
+		count = 0
+	}
+	b, _ := p.pool.Get().(*SnowflakeBatch)
+	if b == nil {
+		b = &SnowflakeBatch{pool: p}
+	}
+	if cap(b.ids) < count {
+		b.ids = make([]Snowflake, count)
+	}
+	b.ids = b.ids[:count]
+	b.ids = b.ids[:self.fill(b.ids)]
+	return b
+}
+
+// ReserveBlock reserves n consecutive IDs, [start, end], for a client to
+// hand out itself, e.g. while offline. The node moves its sequence past
+// the block, so it never issues those IDs. A block lies within a single
+// millisecond, so n may be at most the sequence capacity (4096 by
+// default); ErrSequenceOutOfRange is returned otherwise. Secure nodes do
+// not issue sequences in order and cannot reserve blocks.
+func (self *SnowflakeNode) ReserveBlock(n int) (start Snowflake, end Snowflake, err error) {
+	if n < 1 || int64(n) > self.seqStep+1 || self.secure {
+		return 0, 0, ErrSequenceOutOfRange
+	}
+
+	self.mutex.Lock()
+	start, end, err = self.reserveBlock(n)
+	waits := self.takeWaits()
+	self.mutex.Unlock()
+	self.reportWaits(waits)
+	return start, end, err
+}
+
+func (self *SnowflakeNode) reserveBlock(n int) (start Snowflake, end Snowflake, err error) {
+	now := self.millis()
+	if now < self.time {
+		if now, err = self.waitPast(self.time-1, ErrClockBackwards); err != nil {
+			return 0, 0, err
+		}
+	}
+	self.resumeBackdated(now)
+	first := int64(0)
+	if now == self.time {
+		first = self.sequence + 1
+		if first+int64(n)-1 > self.seqStep {
+			// Not enough room left in this millisecond
+			if now, err = self.waitExhausted(self.time); err != nil {
+				return 0, 0, err
+			}
+			first = 0
+		}
+	}
+	self.goLive(now)
+	self.time = now
+	self.sequence = first + int64(n) - 1
+	return self.compose(now, first), self.compose(now, self.sequence), nil
+}
+
+// WarmSequence moves the node's sequence past count sequence numbers
+// without issuing them, e.g. to skip IDs a crashed predecessor with the
+// same node ID may have issued in the current millisecond. Like
+// ReserveBlock it moves on to the next millisecond if the current one
+// does not have count left, and fails with ErrSequenceOutOfRange if
+// count exceeds the sequence capacity or the node is secure. A count of
+// zero does nothing.
+func (self *SnowflakeNode) WarmSequence(count int) error {
+	if count == 0 {
+		return nil
+	}
+	_, _, err := self.ReserveBlock(count)
+	return err
+}
+
+// NextWithTimestamp returns a new ID with the timestamp of t, e.g. to
+// give imported records IDs matching their original creation time. The
+// node's sequence is consumed for that millisecond, so if t falls in the
+// millisecond Next is currently issuing in, the two share a counter.
+// Earlier milliseconds get their own counters, but only before the
+// node's first live ID, which drops them: the node does not know
+// which sequences it used in milliseconds it has moved past, so those
+// fail with ErrTimestampInUse, as do milliseconds up to a state passed to
+// Restore. If the node goes live in a millisecond it back-dated into, it
+// continues that millisecond's counter. It also fails for t before the epoch or
+// after the clock, and with ErrExhausted once a millisecond's sequence
+// space is used up.
+func (self *SnowflakeNode) NextWithTimestamp(t time.Time) (Snowflake, error) {
+	ms := int64(t.Sub(self.epoch) / self.timeUnit)
+	if t.Before(self.epoch) {
+		return 0, ErrTimestampOverflow
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	if ms > self.millis() {
+		return 0, ErrTimestampOverflow
+	}
+
+	var seq int64
+	switch {
+	case ms == self.time && self.liveSince != 0:
+		seq = self.sequence + 1
+		if seq > self.seqStep || self.secure {
+			return 0, ErrExhausted
+		}
+		self.sequence = seq
+	case self.liveSince != 0 && ms >= self.liveSince, ms <= self.time:
+		return 0, ErrTimestampInUse
+	default:
+		last, ok := self.backdated[ms]
+		if ok {
+			seq = last + 1
+		}
+		if seq > self.seqStep {
+			return 0, ErrExhausted
+		}
+		if self.backdated == nil {
+			self.backdated = make(map[int64]int64)
+		}
+		self.backdated[ms] = seq
+	}
+	return self.compose(ms, seq), nil
+}
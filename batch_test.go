@@ -0,0 +1,192 @@
+package snowflake
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextBatchPooled(t *testing.T) {
+	node := NewSnowflakeNode(8)
+	var pool BatchPool
+
+	var last Snowflake
+	for round := 0; round < 3; round++ {
+		b := node.NextBatchPooled(&pool, 100)
+		if b.Len() != 100 {
+			t.Fatalf("(1) Batch has %d IDs, expected 100!", b.Len())
+		}
+		for i := 0; i < b.Len(); i++ {
+			if b.Get(i) <= last {
+				t.Errorf("(2) ID %d is not after %d!", b.Get(i), last)
+			}
+			last = b.Get(i)
+		}
+		b.Release()
+	}
+
+	if ids := node.NextN(10); len(ids) != 10 || ids[0] <= last {
+		t.Errorf("(3) NextN returned %v!", ids)
+	}
+
+	if ids := node.NextN(-1); len(ids) != 0 {
+		t.Errorf("(4) NextN(-1) returned %v!", ids)
+	}
+	if b := node.NextBatchPooled(&pool, -1); b.Len() != 0 {
+		t.Errorf("(5) NextBatchPooled(-1) returned %d IDs!", b.Len())
+	}
+}
+
+var batchSink []Snowflake
+
+func BenchmarkNextN(b *testing.B) {
+	node := NewSnowflakeNode(8)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		batchSink = node.NextN(64)
+	}
+}
+
+func BenchmarkNextBatchPooled(b *testing.B) {
+	node := NewSnowflakeNode(8)
+	var pool BatchPool
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		batch := node.NextBatchPooled(&pool, 64)
+		for j := 0; j < batch.Len(); j++ {
+			_ = batch.Get(j)
+		}
+		batch.Release()
+	}
+}
+
+func TestReserveBlock(t *testing.T) {
+	node := NewSnowflakeNode(8)
+	before := node.Next()
+
+	start, end, err := node.ReserveBlock(1000)
+	if err != nil {
+		t.Fatalf("(1) Could not reserve a block: %v", err)
+	}
+	if end-start != 999 || start <= before {
+		t.Errorf("(2) Reserved [%d, %d] after %d!", start, end, before)
+	}
+	for i := 0; i < 5000; i++ {
+		if sf := node.Next(); sf >= start && sf <= end {
+			t.Fatalf("(3) Next reissued reserved ID %d!", sf)
+		}
+	}
+
+	if _, _, err := node.ReserveBlock(4097); err != ErrSequenceOutOfRange {
+		t.Errorf("(4) Oversized block returned %v!", err)
+	}
+}
+
+func TestWarmSequence(t *testing.T) {
+	now := time.Now()
+	node, _ := New(8,
+		WithClock(func() time.Time { return now }),
+		WithExhaustionStrategy(steppingStrategy{now: &now}))
+	node.Next()
+
+	if err := node.WarmSequence(100); err != nil || node.CurrentSequence() != 100 {
+		t.Errorf("(1) Expected sequence 100 after warming, got %d, %v!", node.CurrentSequence(), err)
+	}
+	if sf := node.Next(); sf.Sequence() != 101 {
+		t.Errorf("(2) Expected the next ID at sequence 101, got %d!", sf.Sequence())
+	}
+	if err := node.WarmSequence(0); err != nil || node.CurrentSequence() != 101 {
+		t.Errorf("(3) Warming by zero moved the sequence to %d, %v!", node.CurrentSequence(), err)
+	}
+
+	// Not enough room left in this millisecond
+	last := node.CurrentTime()
+	if err := node.WarmSequence(4000); err != nil || node.CurrentTime() != last+1 || node.CurrentSequence() != 3999 {
+		t.Errorf("(4) Expected sequence 3999 of the next millisecond, got %d at %d, %v!", node.CurrentSequence(), node.CurrentTime(), err)
+	}
+
+	if err := node.WarmSequence(4097); err != ErrSequenceOutOfRange {
+		t.Errorf("(5) Oversized warm-up returned %v!", err)
+	}
+}
+
+func TestNextWithTimestamp(t *testing.T) {
+	now := time.Now()
+	node, _ := New(8, WithClock(func() time.Time { return now }))
+	past := now.Add(-time.Hour)
+
+	a, err := node.NextWithTimestamp(past)
+	if err != nil {
+		t.Fatalf("(1) Could not back-date an ID: %v", err)
+	}
+	b, _ := node.NextWithTimestamp(past)
+	if b != a+1 || a.UnixMillis() != past.UnixMilli() {
+		t.Errorf("(2) Back-dated IDs %d and %d do not share a millisecond counter!", a, b)
+	}
+
+	node.Next()
+	now = now.Add(5 * time.Millisecond)
+	live := node.Next()
+	if sf, err := node.NextWithTimestamp(now); err != nil || sf != live+1 {
+		t.Errorf("(3) Back-dating into the current millisecond returned %d, %v!", sf, err)
+	}
+	if _, err := node.NextWithTimestamp(now.Add(-2 * time.Millisecond)); err != ErrTimestampInUse {
+		t.Errorf("(4) Back-dating into a past live millisecond returned %v!", err)
+	}
+	if _, err := node.NextWithTimestamp(time.UnixMilli(baseEpoch - 1)); err != ErrTimestampOverflow {
+		t.Errorf("(5) Time before the epoch returned %v!", err)
+	}
+	if _, err := node.NextWithTimestamp(now.Add(time.Hour)); err != ErrTimestampOverflow {
+		t.Errorf("(6) Future time returned %v!", err)
+	}
+}
+
+func TestNextWithTimestampThenNext(t *testing.T) {
+	now := time.Now()
+	node, _ := New(8, WithClock(func() time.Time { return now }))
+
+	a, err := node.NextWithTimestamp(now)
+	if err != nil {
+		t.Fatalf("(1) Could not back-date an ID: %v", err)
+	}
+	if b := node.Next(); b <= a {
+		t.Errorf("(2) Live ID %d in the back-dated millisecond is not after %d!", b, a)
+	}
+
+	state := node.Snapshot()
+	restored, _ := New(8, WithClock(func() time.Time { return now }))
+	restored.Restore(state)
+	if sf, err := restored.NextWithTimestamp(now); err != ErrTimestampInUse {
+		t.Errorf("(3) Back-dating into a restored millisecond returned %d, %v!", sf, err)
+	}
+	if sf, err := restored.NextWithTimestamp(now.Add(-time.Hour)); err != ErrTimestampInUse {
+		t.Errorf("(4) Back-dating before a restored state returned %d, %v!", sf, err)
+	}
+}
+
+func TestBackdatedFreedOnGoingLive(t *testing.T) {
+	now := time.Now()
+	node, _ := New(8, WithClock(func() time.Time { return now }))
+	for i := 1; i <= 100; i++ {
+		if _, err := node.NextWithTimestamp(now.Add(-time.Duration(i) * time.Millisecond)); err != nil {
+			t.Fatalf("(1) Could not back-date an ID: %v", err)
+		}
+	}
+	if len(node.backdated) != 100 {
+		t.Fatalf("(2) Expected 100 back-dated counters, got %d!", len(node.backdated))
+	}
+
+	node.Next()
+	if node.backdated != nil {
+		t.Errorf("(3) %d back-dated counters kept after going live!", len(node.backdated))
+	}
+	if _, err := node.NextWithTimestamp(now.Add(-200 * time.Millisecond)); err != ErrTimestampInUse {
+		t.Errorf("(4) Back-dating after going live returned %v!", err)
+	}
+
+	reserved, _ := New(8, WithClock(func() time.Time { return now }))
+	reserved.NextWithTimestamp(now.Add(-time.Millisecond))
+	reserved.ReserveBlock(10)
+	if reserved.backdated != nil {
+		t.Errorf("(5) Back-dated counters kept after ReserveBlock!")
+	}
+}
@@ -0,0 +1,34 @@
+package snowflake
+
+// DiscordMentionKind selects the mention syntax used by ToDiscordString.
+type DiscordMentionKind int
+
+const (
+	DiscordUser DiscordMentionKind = iota
+	DiscordChannel
+	DiscordRole
+	DiscordSlashCommand
+)
+
+// ToDiscordString formats sf as a Discord mention of the given kind,
+// e.g. "<@123>" for a user. Slash command mentions also need the
+// command name, which this method does not have, so for
+// DiscordSlashCommand (or an unknown kind) it returns the bare ID; use
+// ToDiscordCommandString instead.
+func (sf Snowflake) ToDiscordString(kind DiscordMentionKind) string {
+	switch kind {
+	case DiscordUser:
+		return "<@" + sf.String() + ">"
+	case DiscordChannel:
+		return "<#" + sf.String() + ">"
+	case DiscordRole:
+		return "<@&" + sf.String() + ">"
+	}
+	return sf.String()
+}
+
+// ToDiscordCommandString formats sf as a mention of the slash command
+// name, e.g. "</ping:123>".
+func (sf Snowflake) ToDiscordCommandString(name string) string {
+	return "</" + name + ":" + sf.String() + ">"
+}
@@ -0,0 +1,21 @@
+package snowflake
+
+import "testing"
+
+func TestToDiscordString(t *testing.T) {
+	sf := Snowflake(1234567890)
+	for kind, want := range map[DiscordMentionKind]string{
+		DiscordUser:         "<@1234567890>",
+		DiscordChannel:      "<#1234567890>",
+		DiscordRole:         "<@&1234567890>",
+		DiscordSlashCommand: "1234567890",
+	} {
+		if got := sf.ToDiscordString(kind); got != want {
+			t.Errorf("(1) Mention kind %d gave %q, expected %q!", kind, got, want)
+		}
+	}
+
+	if got := sf.ToDiscordCommandString("ping"); got != "</ping:1234567890>" {
+		t.Errorf("(2) Command mention gave %q!", got)
+	}
+}
@@ -0,0 +1,99 @@
+package snowflake
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// nodeStats accumulates clock and sequence events. Fields are updated
+// and read atomically so reports do not need the node's lock.
+type nodeStats struct {
+	issued      int64
+	exhaustions int64
+	driftEvents int64
+	maxDrift    int64 // nanoseconds
+	totalDrift  int64 // nanoseconds
+	lastDriftAt int64 // Unix nanoseconds
+
+	// metrics, if set, is told of each event as well.
+	metrics SnowflakeMetrics
+}
+
+func (s *nodeStats) generated() {
+	atomic.AddInt64(&s.issued, 1)
+	if s.metrics != nil {
+		s.metrics.IncrGenerated()
+	}
+}
+
+func (s *nodeStats) exhausted() {
+	atomic.AddInt64(&s.exhaustions, 1)
+	if s.metrics != nil {
+		s.metrics.IncrExhausted()
+	}
+}
+
+// drifted records the clock reading d behind the last ID issued.
+func (s *nodeStats) drifted(d time.Duration) {
+	atomic.AddInt64(&s.driftEvents, 1)
+	atomic.AddInt64(&s.totalDrift, int64(d))
+	for {
+		old := atomic.LoadInt64(&s.maxDrift)
+		if int64(d) <= old || atomic.CompareAndSwapInt64(&s.maxDrift, old, int64(d)) {
+			break
+		}
+	}
+	atomic.StoreInt64(&s.lastDriftAt, time.Now().UnixNano())
+	if s.metrics != nil {
+		s.metrics.ObserveDrift(d)
+	}
+}
+
+// DriftReport summarises the clock behaviour a node has seen.
+// TotalExhaustions counts the times a millisecond's sequence space ran
+// out; a drift event is the clock stepping back behind the last ID
+// issued, counted once per step however many IDs are issued while it
+// stays behind, and MaxDrift and TotalDrift measure how far behind it
+// was when it stepped.
+type DriftReport struct {
+	TotalExhaustions, TotalDriftEvents int64
+	MaxDrift, TotalDrift               time.Duration
+	LastDriftAt                        time.Time
+}
+
+// DriftThresholds are the limits DriftReport.IsHealthy checks against.
+// Zero fields are not checked.
+type DriftThresholds struct {
+	MaxExhaustions int64
+	MaxDriftEvents int64
+	MaxDrift       time.Duration
+}
+
+// DriftReport returns the clock metrics accumulated by the node. It does
+// not take the node's lock.
+func (self *SnowflakeNode) DriftReport() DriftReport {
+	r := DriftReport{
+		TotalExhaustions: atomic.LoadInt64(&self.stats.exhaustions),
+		TotalDriftEvents: atomic.LoadInt64(&self.stats.driftEvents),
+		MaxDrift:         time.Duration(atomic.LoadInt64(&self.stats.maxDrift)),
+		TotalDrift:       time.Duration(atomic.LoadInt64(&self.stats.totalDrift)),
+	}
+	if last := atomic.LoadInt64(&self.stats.lastDriftAt); last != 0 {
+		r.LastDriftAt = time.Unix(0, last)
+	}
+	return r
+}
+
+// IsHealthy reports whether r is within thresholds.
+func (r DriftReport) IsHealthy(thresholds DriftThresholds) bool {
+	if thresholds.MaxExhaustions > 0 && r.TotalExhaustions > thresholds.MaxExhaustions {
+		return false
+	}
+	if thresholds.MaxDriftEvents > 0 && r.TotalDriftEvents > thresholds.MaxDriftEvents {
+		return false
+	}
+	if thresholds.MaxDrift > 0 && r.MaxDrift > thresholds.MaxDrift {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package snowflake
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDriftReport(t *testing.T) {
+	now := time.Now()
+	node, _ := New(1,
+		WithClock(func() time.Time { return now }),
+		WithMonotonicClock())
+
+	node.Next()
+	now = now.Add(-10 * time.Millisecond)
+	node.Next()
+	now = now.Add(-20 * time.Millisecond)
+	for i := 0; i < 4096; i++ {
+		node.Next()
+	}
+
+	r := node.DriftReport()
+	// One event per step back, however many IDs were issued behind it
+	if r.TotalDriftEvents != 2 {
+		t.Errorf("(1) Expected 2 drift events, got %d!", r.TotalDriftEvents)
+	}
+	if r.MaxDrift != 30*time.Millisecond || r.TotalDrift != 40*time.Millisecond {
+		t.Errorf("(2) Expected a max drift of 30ms and 40ms in total, got %v and %v!", r.MaxDrift, r.TotalDrift)
+	}
+	if r.TotalExhaustions != 1 {
+		t.Errorf("(3) Expected 1 exhaustion, got %d!", r.TotalExhaustions)
+	}
+	if r.LastDriftAt.IsZero() {
+		t.Errorf("(4) LastDriftAt was not set!")
+	}
+
+	if !r.IsHealthy(DriftThresholds{}) {
+		t.Errorf("(5) Report is unhealthy without thresholds!")
+	}
+	if r.IsHealthy(DriftThresholds{MaxDrift: 25 * time.Millisecond}) {
+		t.Errorf("(6) Report with 30ms drift is healthy at a 25ms limit!")
+	}
+}
+
+func TestDriftReportBlocking(t *testing.T) {
+	var mu sync.Mutex
+	now := time.Now()
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	node, _ := New(1, WithClock(clock))
+
+	node.Next()
+	mu.Lock()
+	now = now.Add(-10 * time.Millisecond)
+	mu.Unlock()
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		now = now.Add(11 * time.Millisecond)
+		mu.Unlock()
+	}()
+	for i := 0; i < 100; i++ {
+		node.Next()
+	}
+	if r := node.DriftReport(); r.TotalDriftEvents != 1 || r.MaxDrift != 10*time.Millisecond {
+		t.Errorf("(1) Expected one 10ms drift event while waiting, got %d and %v!", r.TotalDriftEvents, r.MaxDrift)
+	}
+}
@@ -0,0 +1,224 @@
+package snowflake
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// CSVField returns sf as a bare decimal for use as a CSV cell. Unlike
+// MarshalJSON, the result is never quoted.
+func (sf Snowflake) CSVField() string {
+	return strconv.FormatInt(int64(sf), 10)
+}
+
+// ParseCSVField parses a CSV cell written by CSVField, ignoring
+// surrounding whitespace.
+func ParseCSVField(s string) (Snowflake, error) {
+	return ParseSnowflake(strings.TrimSpace(s))
+}
+
+// ForEachLine parses r as one decimal ID per line, calling fn for each
+// and skipping blank lines. It stops at the first parse error, which
+// reports its line number, or the first error returned by fn. Only one
+// line is held in memory at a time.
+func ForEachLine(r io.Reader, fn func(Snowflake) error) error {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		sf, err := ParseSnowflake(text)
+		if err != nil {
+			return fmt.Errorf("snowflake: line %d: %w", line, err)
+		}
+		if err := fn(sf); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// DecodeLines is like ForEachLine but collects the IDs into a slice.
+func DecodeLines(r io.Reader) ([]Snowflake, error) {
+	var ids []Snowflake
+	err := ForEachLine(r, func(sf Snowflake) error {
+		ids = append(ids, sf)
+		return nil
+	})
+	return ids, err
+}
+
+// Wide returns sf as a 96-bit big-endian value with the timestamp field
+// zero-extended by 32 bits; node and sequence keep their positions in
+// the low 22 bits. A future Snowflake96 type laying out
+//
+//	timestamp (74 bits) | node (10 bits) | sequence (12 bits)
+//
+// in 12 big-endian bytes produces exactly these bytes for every existing
+// ID, so old and new IDs sort together byte-wise.
+func (sf Snowflake) Wide() [12]byte {
+	var b [12]byte
+	binary.BigEndian.PutUint64(b[4:], uint64(sf))
+	return b
+}
+
+// ErrParseList reports which entry of a list could not be parsed.
+// Index is 1-based.
+type ErrParseList struct {
+	Index int
+	Err   error
+}
+
+func (e ErrParseList) Error() string {
+	return fmt.Sprintf("snowflake: list entry %d: %v", e.Index, e.Err)
+}
+
+func (e ErrParseList) Unwrap() error {
+	return e.Err
+}
+
+// ParseSnowflakeList parses a list of decimal IDs separated by sep, such
+// as the "123,456,789" of an ids query parameter. Whitespace around each
+// entry is ignored and an empty string gives an empty list. The first bad
+// entry is reported as an ErrParseList.
+func ParseSnowflakeList(s string, sep string) ([]Snowflake, error) {
+	if strings.TrimSpace(s) == "" {
+		return []Snowflake{}, nil
+	}
+	parts := strings.Split(s, sep)
+	ids := make([]Snowflake, len(parts))
+	for i, part := range parts {
+		sf, err := ParseSnowflake(strings.TrimSpace(part))
+		if err != nil {
+			return nil, ErrParseList{Index: i + 1, Err: err}
+		}
+		ids[i] = sf
+	}
+	return ids, nil
+}
+
+// SnowflakeSlice is a list of IDs.
+type SnowflakeSlice []Snowflake
+
+// Join formats the IDs as decimals separated by sep, the inverse of
+// ParseSnowflakeList.
+func (ids SnowflakeSlice) Join(sep string) string {
+	parts := make([]string, len(ids))
+	for i, sf := range ids {
+		parts[i] = strconv.FormatInt(int64(sf), 10)
+	}
+	return strings.Join(parts, sep)
+}
+
+// PackedLE encodes sf for a storage format that keeps the three fields
+// in order from the lowest bit of a little-endian word: the timestamp in
+// bits 0-40, the node in bits 41-50 and the sequence in bits 51-62. This
+// is not the canonical form, which is the big-endian int64 of Bytes and
+// MarshalBinary, and should only be used for interop with that format.
+func (sf Snowflake) PackedLE() [8]byte {
+	ms, node, seq := sf.parts()
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], uint64(ms|node<<DefaultLayout.TimestampBits|seq<<(DefaultLayout.TimestampBits+baseNodeBits)))
+	return b
+}
+
+// UnpackLE decodes the form written by PackedLE.
+func UnpackLE(b [8]byte) (Snowflake, error) {
+	v := binary.LittleEndian.Uint64(b[:])
+	if v>>63 != 0 {
+		return 0, ErrInvalidSnowflake
+	}
+	ms := int64(v & (1<<DefaultLayout.TimestampBits - 1))
+	node := int64(v>>DefaultLayout.TimestampBits) & (1<<baseNodeBits - 1)
+	seq := int64(v >> (DefaultLayout.TimestampBits + baseNodeBits))
+	return DefaultLayout.Pack(ms, node, seq)
+}
+
+// ToShortID returns a short base36 code made from the low 64-truncateBits
+// bits of sf, discarding its oldest high bits, zero-padded to the width
+// needed for that many bits. With truncateBits = 23 the code keeps the
+// node, sequence and low 19 timestamp bits in 8 characters, which is
+// unique among one node's IDs for about 8.7 minutes; codes repeat after
+// that, so they are only suitable where that window suffices.
+func (sf Snowflake) ToShortID(truncateBits uint8) string {
+	if truncateBits >= 64 {
+		return ""
+	}
+	bits := 64 - uint(truncateBits)
+	max := ^uint64(0) >> (64 - bits)
+	width := 0
+	for v := max; v > 0; v /= 36 {
+		width++
+	}
+	code := strconv.FormatUint(uint64(sf)&max, 36)
+	return strings.Repeat("0", width-len(code)) + code
+}
+
+// Base64URL returns the 8 big-endian bytes of sf in unpadded URL-safe
+// base64, which is always 11 characters.
+func (sf Snowflake) Base64URL() string {
+	b := sf.Bytes()
+	return base64.RawURLEncoding.EncodeToString(b[:])
+}
+
+// SnowflakeFromBase64URL parses the output of Base64URL. Strings that
+// are not 11 characters long, or that do not decode to a valid
+// snowflake, are rejected.
+func SnowflakeFromBase64URL(s string) (Snowflake, error) {
+	if len(s) != 11 {
+		return 0, fmt.Errorf("snowflake: base64url form must be 11 characters, got %d", len(s))
+	}
+	b, err := base64.RawURLEncoding.Strict().DecodeString(s)
+	if err != nil {
+		return 0, err
+	}
+	return SnowflakeFromUint64(binary.BigEndian.Uint64(b))
+}
+
+// ParseBase64URL is SnowflakeFromBase64URL, named to match ParseSnowflake
+// and ParseReferenceCode.
+func ParseBase64URL(s string) (Snowflake, error) {
+	return SnowflakeFromBase64URL(s)
+}
+
+// ReferenceAlphabet is the 16 symbols of ReferenceCode, in digit order.
+// It has no vowels, so codes cannot spell words.
+const ReferenceAlphabet = "0123456789BCDFGH"
+
+// ReferenceCode returns sf in base 16 over ReferenceAlphabet, for
+// customer-facing references such as support tickets. Codes are not
+// padded: current IDs give 15 characters, and none give more than 16.
+func (sf Snowflake) ReferenceCode() string {
+	var code [16]byte
+	i := len(code)
+	for v := uint64(sf); ; v >>= 4 {
+		i--
+		code[i] = ReferenceAlphabet[v&0xf]
+		if v < 16 {
+			break
+		}
+	}
+	return string(code[i:])
+}
+
+// ParseReferenceCode parses the output of ReferenceCode, ignoring case.
+func ParseReferenceCode(s string) (Snowflake, error) {
+	if s == "" || len(s) > 16 {
+		return 0, fmt.Errorf("snowflake: invalid reference code %q", s)
+	}
+	var v uint64
+	for i := 0; i < len(s); i++ {
+		d := strings.IndexByte(ReferenceAlphabet, strings.ToUpper(s[i : i+1])[0])
+		if d < 0 {
+			return 0, fmt.Errorf("snowflake: invalid reference code %q", s)
+		}
+		v = v<<4 | uint64(d)
+	}
+	return SnowflakeFromUint64(v)
+}
@@ -0,0 +1,164 @@
+package snowflake
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCSVField(t *testing.T) {
+	sf := Snowflake(2856524282194824821)
+	field := sf.CSVField()
+	if field != "2856524282194824821" {
+		t.Errorf("(1) CSVField returned %q!", field)
+	}
+
+	back, err := ParseCSVField(" " + field + " ")
+	if err != nil || back != sf {
+		t.Errorf("(2) ParseCSVField returned %d, %v!", back, err)
+	}
+
+	if _, err := ParseCSVField(`"` + field + `"`); err == nil {
+		t.Errorf("(3) Quoted field was accepted!")
+	}
+}
+
+func TestDecodeLines(t *testing.T) {
+	ids, err := DecodeLines(strings.NewReader("1\n\n  22 \r\n333"))
+	if err != nil || len(ids) != 3 || ids[0] != 1 || ids[1] != 22 || ids[2] != 333 {
+		t.Errorf("(1) DecodeLines returned %v, %v!", ids, err)
+	}
+
+	_, err = DecodeLines(strings.NewReader("1\n2\n\nbad\n4"))
+	if err == nil || !strings.Contains(err.Error(), "line 4") {
+		t.Errorf("(2) Expected an error on line 4, got %v!", err)
+	}
+}
+
+func TestWide(t *testing.T) {
+	node := NewSnowflakeNode(3)
+	a, b := node.Next(), node.Next()
+	wa, wb := a.Wide(), b.Wide()
+	if bytes.Compare(wa[:], wb[:]) >= 0 {
+		t.Errorf("(1) Wide forms of %d and %d do not sort in order!", a, b)
+	}
+
+	raw := a.Bytes()
+	if !bytes.Equal(wa[:4], []byte{0, 0, 0, 0}) || !bytes.Equal(wa[4:], raw[:]) {
+		t.Errorf("(2) Wide form %x does not extend %x!", wa, raw)
+	}
+}
+
+func TestParseSnowflakeList(t *testing.T) {
+	if ids, err := ParseSnowflakeList("", ","); err != nil || len(ids) != 0 {
+		t.Errorf("(1) Empty list returned %v, %v!", ids, err)
+	}
+	if ids, err := ParseSnowflakeList("123", ","); err != nil || len(ids) != 1 || ids[0] != 123 {
+		t.Errorf("(2) Single ID returned %v, %v!", ids, err)
+	}
+
+	ids, err := ParseSnowflakeList("123 , 456,\t789", ",")
+	if err != nil || len(ids) != 3 || ids[2] != 789 {
+		t.Errorf("(3) Spaced list returned %v, %v!", ids, err)
+	}
+	if joined := SnowflakeSlice(ids).Join(","); joined != "123,456,789" {
+		t.Errorf("(4) Join returned %q!", joined)
+	}
+
+	_, err = ParseSnowflakeList("123,abc,789", ",")
+	var listErr ErrParseList
+	if !errors.As(err, &listErr) || listErr.Index != 2 || !errors.Is(err, strconv.ErrSyntax) {
+		t.Errorf("(5) Bad second entry returned %v!", err)
+	}
+}
+
+func TestPackedLE(t *testing.T) {
+	sf := Snowflake(1000<<22 | 5<<12 | 3)
+	b := sf.PackedLE()
+	if b[0] != 0xe8 || b[1] != 0x03 {
+		t.Errorf("(1) Timestamp is not in the low bytes of %x!", b)
+	}
+
+	back, err := UnpackLE(b)
+	if err != nil || back != sf {
+		t.Errorf("(2) Round trip of %d returned %d, %v!", sf, back, err)
+	}
+
+	live := NewSnowflakeNode(1023).Next()
+	if back, err := UnpackLE(live.PackedLE()); err != nil || back != live {
+		t.Errorf("(3) Round trip of %d returned %d, %v!", live, back, err)
+	}
+}
+
+func TestToShortID(t *testing.T) {
+	node := NewSnowflakeNode(5)
+	seen := make(map[string]bool)
+	for i := 0; i < 10000; i++ {
+		code := node.Next().ToShortID(23)
+		if len(code) != 8 {
+			t.Fatalf("(1) Short ID %q is not 8 characters!", code)
+		}
+		if seen[code] {
+			t.Fatalf("(2) Duplicate short ID %q!", code)
+		}
+		seen[code] = true
+	}
+
+	if code := Snowflake(35).ToShortID(42); code != "0000z" {
+		t.Errorf("(3) 35 with 22 bits kept gave %q!", code)
+	}
+}
+
+func TestBase64URL(t *testing.T) {
+	node := NewSnowflakeNode(5)
+	for _, sf := range []Snowflake{0, 1, 1<<62 | 1, MaxSnowflake, node.Next(), node.Next()} {
+		code := sf.Base64URL()
+		if len(code) != 11 || strings.ContainsAny(code, "+/=") {
+			t.Errorf("(1) Code %q for %d is not 11 URL-safe characters!", code, sf)
+		}
+		if back, err := SnowflakeFromBase64URL(code); err != nil || back != sf {
+			t.Errorf("(2) Code %q parsed back to %d, %v, expected %d!", code, back, err, sf)
+		}
+	}
+
+	for _, s := range []string{"", "AAAAAAAAAA", "AAAAAAAAAAAA", "AAAAAAAAAA+"} {
+		if _, err := SnowflakeFromBase64URL(s); err == nil {
+			t.Errorf("(3) Malformed code %q was not rejected!", s)
+		}
+	}
+	if _, err := SnowflakeFromBase64URL("gAAAAAAAAAA"); err != ErrInvalidSnowflake {
+		t.Errorf("(4) Code with the sign bit set was not rejected, got %v!", err)
+	}
+
+	sf := node.Next()
+	if back, err := ParseBase64URL(sf.Base64URL()); err != nil || back != sf {
+		t.Errorf("(5) ParseBase64URL returned %d, %v, expected %d!", back, err, sf)
+	}
+	if _, err := ParseBase64URL("AAAAAAAAAAAA"); err == nil {
+		t.Errorf("(6) ParseBase64URL accepted 12 characters!")
+	}
+}
+
+func TestReferenceCode(t *testing.T) {
+	node := NewSnowflakeNode(5)
+	for _, sf := range []Snowflake{0, 15, 16, MaxSnowflake, node.Next()} {
+		code := sf.ReferenceCode()
+		if strings.ContainsAny(code, "AEIOUaeiou") || len(code) > 16 {
+			t.Errorf("(1) Code %q for %d is not vowel-free and at most 16 characters!", code, sf)
+		}
+		if back, err := ParseReferenceCode(strings.ToLower(code)); err != nil || back != sf {
+			t.Errorf("(2) Code %q parsed back to %d, %v, expected %d!", code, back, err, sf)
+		}
+	}
+	if code := Snowflake(0x1b).ReferenceCode(); code != "1C" {
+		t.Errorf("(3) Expected code 1C for 27, got %q!", code)
+	}
+
+	for _, s := range []string{"", "1A", "HHHHHHHHHHHHHHHH", "00000000000000000"} {
+		if _, err := ParseReferenceCode(s); err == nil {
+			t.Errorf("(4) Malformed code %q was not rejected!", s)
+		}
+	}
+}
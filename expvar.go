@@ -0,0 +1,63 @@
+package snowflake
+
+import (
+	"expvar"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// SnowflakeCounter is an int64 counter that implements expvar.Var. It is
+// read and updated atomically.
+type SnowflakeCounter int64
+
+func (c *SnowflakeCounter) Add(delta int64) {
+	atomic.AddInt64((*int64)(c), delta)
+}
+
+func (c *SnowflakeCounter) Value() int64 {
+	return atomic.LoadInt64((*int64)(c))
+}
+
+// String implements expvar.Var.
+func (c *SnowflakeCounter) String() string {
+	return strconv.FormatInt(c.Value(), 10)
+}
+
+// WithExpvarExport publishes the node's metrics as an expvar.Map under
+// name, so they appear on /debug/vars: "generated" counts IDs issued by
+// Next and the batch methods, and "exhaustions", "drift_events" and
+// "max_drift_ns" are as in DriftReport. The map is only published once
+// New has otherwise succeeded. Since expvar names are global,
+// construction fails if name is already published.
+func WithExpvarExport(name string) Option {
+	return func(node *SnowflakeNode) error {
+		node.expvarName = name
+		return nil
+	}
+}
+
+// expvarMutex makes checking and publishing an expvar name atomic, since
+// expvar.Publish panics on a name that is already taken.
+var expvarMutex sync.Mutex
+
+// publishExpvar publishes the node's metrics under the name from
+// WithExpvarExport, if any.
+func (self *SnowflakeNode) publishExpvar() error {
+	if self.expvarName == "" {
+		return nil
+	}
+	expvarMutex.Lock()
+	defer expvarMutex.Unlock()
+	if expvar.Get(self.expvarName) != nil {
+		return fmt.Errorf("snowflake: expvar %q already published", self.expvarName)
+	}
+	m := new(expvar.Map)
+	m.Set("generated", (*SnowflakeCounter)(&self.stats.issued))
+	m.Set("exhaustions", (*SnowflakeCounter)(&self.stats.exhaustions))
+	m.Set("drift_events", (*SnowflakeCounter)(&self.stats.driftEvents))
+	m.Set("max_drift_ns", (*SnowflakeCounter)(&self.stats.maxDrift))
+	expvar.Publish(self.expvarName, m)
+	return nil
+}
@@ -0,0 +1,77 @@
+package snowflake
+
+import (
+	"encoding/json"
+	"expvar"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithExpvarExport(t *testing.T) {
+	frozen := time.Now()
+	node, err := New(1,
+		WithClock(func() time.Time { return frozen }),
+		WithExhaustionStrategy(ErrorStrategy{}),
+		WithExpvarExport("snowflake_test_node"))
+	if err != nil {
+		t.Fatalf("(1) Could not create node: %v", err)
+	}
+	for i := 0; i < 4097; i++ {
+		node.Next()
+	}
+
+	var vars map[string]int64
+	if err := json.Unmarshal([]byte(expvar.Get("snowflake_test_node").String()), &vars); err != nil {
+		t.Fatalf("(2) Could not decode published vars: %v", err)
+	}
+	if vars["generated"] != 4096 || vars["exhaustions"] != 1 || vars["drift_events"] != 0 {
+		t.Errorf("(3) Unexpected published vars %v!", vars)
+	}
+
+	if _, err := New(2, WithExpvarExport("snowflake_test_node")); err == nil {
+		t.Errorf("(4) Publishing the same name twice did not fail!")
+	}
+
+	if _, err := New(5000, WithExpvarExport("snowflake_test_rejected")); err != ErrNodeIDOutOfRange {
+		t.Errorf("(5) Out of range node ID returned %v!", err)
+	}
+	if _, err := New(1, WithExpvarExport("snowflake_test_rejected")); err != nil {
+		t.Errorf("(6) Name of a rejected node was left published: %v", err)
+	}
+}
+
+func TestWithExpvarExportConcurrent(t *testing.T) {
+	const goroutines = 16
+	errs := make(chan error, goroutines)
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			_, err := New(id, WithExpvarExport("snowflake_test_concurrent"))
+			errs <- err
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+
+	published := 0
+	for err := range errs {
+		if err == nil {
+			published++
+		}
+	}
+	if published != 1 {
+		t.Errorf("(1) Expected exactly one node to publish the name, got %d!", published)
+	}
+}
+
+func TestSnowflakeCounter(t *testing.T) {
+	var c SnowflakeCounter
+	c.Add(41)
+	c.Add(1)
+	if c.Value() != 42 || c.String() != "42" {
+		t.Errorf("(1) Expected counter 42, got %d (%q)!", c.Value(), c.String())
+	}
+}
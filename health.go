@@ -0,0 +1,39 @@
+package snowflake
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Healthy reports unhealthy once the timestamp field is within this long
+// of overflowing.
+const overflowWarning = 30 * 24 * time.Hour
+
+// ErrNodeClosed is returned by Healthy for a node that has been closed.
+var ErrNodeClosed = errors.New("snowflake: node closed")
+
+// Healthy returns nil if the node can currently generate IDs normally,
+// or an error describing why not: it has been closed, the clock is
+// behind the last ID issued (so Next would wait), or the timestamp field
+// is close to overflowing. It does not issue or consume an ID, so it is
+// suitable for readiness probes.
+func (self *SnowflakeNode) Healthy() error {
+	self.mutex.Lock()
+	closed, last := self.closed, self.time
+	now := self.millis()
+	self.mutex.Unlock()
+
+	if closed {
+		return ErrNodeClosed
+	}
+	if now < last && !self.monotonic {
+		return fmt.Errorf("%w: clock is %v behind the last ID issued", ErrClockBackwards, time.Duration(last-now)*self.timeUnit)
+	}
+	limit := int64(1)<<self.epochBits - 1
+	// Compare in time units: wide timestamp fields overflow a Duration
+	if left := limit - now; left < int64(overflowWarning/self.timeUnit) {
+		return fmt.Errorf("%w: timestamp field overflows in %v", ErrTimestampOverflow, time.Duration(left)*self.timeUnit)
+	}
+	return nil
+}
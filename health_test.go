@@ -0,0 +1,43 @@
+package snowflake
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHealthy(t *testing.T) {
+	now := time.Now()
+	node, _ := New(1, WithClock(func() time.Time { return now }))
+	if err := node.Healthy(); err != nil {
+		t.Errorf("(1) New node is unhealthy: %v", err)
+	}
+
+	before := node.Snapshot()
+	node.Next()
+	now = now.Add(-time.Second)
+	if err := node.Healthy(); !errors.Is(err, ErrClockBackwards) {
+		t.Errorf("(2) Node with a clock behind is healthy: %v", err)
+	}
+	if after := node.Snapshot(); after.Time == before.Time {
+		t.Errorf("(3) Snapshot did not see the generated ID!")
+	}
+	node.Healthy()
+	if node.Snapshot().Sequence != 0 {
+		t.Errorf("(4) Healthy consumed a sequence number!")
+	}
+
+	now = now.Add(2 * time.Second)
+	node.Close()
+	if err := node.Healthy(); err != ErrNodeClosed {
+		t.Errorf("(5) Closed node returned %v!", err)
+	}
+
+	// Timestamp fields this wide have far more than a Duration of room.
+	for nodeBits := uint8(1); nodeBits <= 8; nodeBits++ {
+		wide, _ := NewSnowflakeNodeWithBits(0, nodeBits, 1)
+		if err := wide.Healthy(); err != nil {
+			t.Errorf("(6) Node with %d timestamp bits is unhealthy: %v", wide.Info().TimestampBits, err)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package snowflake
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// KSUIDs count seconds from this Unix time.
+const ksuidEpoch = int64(1400000000)
+
+// Length of a base62-encoded KSUID.
+const ksuidLength = 27
+
+const ksuidAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// ToKSUIDLike returns a 27-character base62 KSUID for sf: its timestamp
+// is the second sf was generated in, and its 16-byte payload is sf
+// itself, zero-padded in front. These sort like real KSUIDs, so they
+// share an order with KSUIDs from other systems down to the second, and
+// within a second they sort as the IDs do.
+func (sf Snowflake) ToKSUIDLike() string {
+	var raw [20]byte
+	secs := (baseEpoch + sf.RawMillis()) / 1000
+	binary.BigEndian.PutUint32(raw[:4], uint32(secs-ksuidEpoch))
+	binary.BigEndian.PutUint64(raw[12:], uint64(sf))
+	return encodeKSUID(raw)
+}
+
+func encodeKSUID(raw [20]byte) string {
+	n := new(big.Int).SetBytes(raw[:])
+	base, mod := big.NewInt(62), new(big.Int)
+	var code [ksuidLength]byte
+	for i := ksuidLength - 1; i >= 0; i-- {
+		n.DivMod(n, base, mod)
+		code[i] = ksuidAlphabet[mod.Int64()]
+	}
+	return string(code[:])
+}
+
+// FromKSUIDLike is a best-effort inverse of ToKSUIDLike. Strings made by
+// ToKSUIDLike give back the original ID. Any other KSUID carries more
+// payload than an ID can hold, so the result only keeps its timestamp,
+// to the second, with the low 22 bits of the payload as node and
+// sequence; distinct KSUIDs may give the same ID. KSUIDs from outside
+// the range of the timestamp field fail with ErrTimestampOverflow.
+func FromKSUIDLike(s string) (Snowflake, error) {
+	if len(s) != ksuidLength {
+		return 0, fmt.Errorf("snowflake: KSUID must be %d characters, got %d", ksuidLength, len(s))
+	}
+	n, base := new(big.Int), big.NewInt(62)
+	for i := 0; i < len(s); i++ {
+		d := strings.IndexByte(ksuidAlphabet, s[i])
+		if d < 0 {
+			return 0, fmt.Errorf("snowflake: invalid KSUID character %q", s[i])
+		}
+		n.Mul(n, base).Add(n, big.NewInt(int64(d)))
+	}
+	if n.BitLen() > 160 {
+		return 0, fmt.Errorf("snowflake: KSUID %q out of range", s)
+	}
+	var raw [20]byte
+	n.FillBytes(raw[:])
+
+	secs := int64(binary.BigEndian.Uint32(raw[:4])) + ksuidEpoch
+	if sf, err := SnowflakeFromUint64(binary.BigEndian.Uint64(raw[12:])); err == nil &&
+		binary.BigEndian.Uint32(raw[4:]) == 0 && binary.BigEndian.Uint32(raw[8:]) == 0 &&
+		(baseEpoch+sf.RawMillis())/1000 == secs {
+		return sf, nil
+	}
+
+	low := int64(binary.BigEndian.Uint64(raw[12:]))
+	return DefaultLayout.Pack(secs*1000-baseEpoch,
+		low>>baseSeqIdBits&(1<<baseNodeBits-1), low&(1<<baseSeqIdBits-1))
+}
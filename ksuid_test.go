@@ -0,0 +1,57 @@
+package snowflake
+
+import (
+	"encoding/binary"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestKSUIDLike(t *testing.T) {
+	node := NewSnowflakeNode(5)
+	ids := []Snowflake{node.Next(), node.Next()}
+	late, _ := Encode(time.Now().Add(time.Second), 1, 0)
+	ids = append(ids, late)
+
+	var codes []string
+	for _, sf := range ids {
+		code := sf.ToKSUIDLike()
+		if len(code) != 27 {
+			t.Errorf("(1) KSUID %q for %d is not 27 characters!", code, sf)
+		}
+		if back, err := FromKSUIDLike(code); err != nil || back != sf {
+			t.Errorf("(2) KSUID %q parsed back to %d, %v, expected %d!", code, back, err, sf)
+		}
+		codes = append(codes, code)
+	}
+	if !sort.StringsAreSorted(codes) {
+		t.Errorf("(3) KSUIDs %v are not in ID order!", codes)
+	}
+
+	// A foreign KSUID: its timestamp survives, its payload does not.
+	want := time.Date(2023, 5, 17, 13, 45, 12, 0, time.UTC)
+	var raw [20]byte
+	binary.BigEndian.PutUint32(raw[:4], uint32(want.Unix()-ksuidEpoch))
+	for i := 4; i < 20; i++ {
+		raw[i] = byte(i * 37)
+	}
+	sf, err := FromKSUIDLike(encodeKSUID(raw))
+	if err != nil {
+		t.Fatalf("(4) Could not parse KSUID: %v", err)
+	}
+	if !sf.Time().Equal(want) {
+		t.Errorf("(5) KSUID time decoded as %v, expected %v!", sf.Time(), want)
+	}
+	if _, err := FromKSUIDLike("0ujtsYcgvSTl8PAuAdqWYSMnLOv"); err != ErrTimestampOverflow {
+		t.Errorf("(6) KSUID from before the epoch was not rejected, got %v!", err)
+	}
+	if sf, err := FromKSUIDLike("aWgEPTl1tmebfsQzFP4bxwgy80V"); err != ErrTimestampOverflow {
+		t.Errorf("(7) Largest KSUID returned %d, %v, expected ErrTimestampOverflow!", sf, err)
+	}
+
+	for _, s := range []string{"", "0ujtsYcgvSTl8PAuAdqWYSMnLO", "0ujtsYcgvSTl8PAuAdqWYSMnLO-", "zzzzzzzzzzzzzzzzzzzzzzzzzzz"} {
+		if _, err := FromKSUIDLike(s); err == nil {
+			t.Errorf("(8) Malformed KSUID %q was not rejected!", s)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package snowflake
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"time"
+)
+
+// BitLayout gives the widths of the three snowflake fields. A valid
+// layout uses exactly 63 bits, leaving the sign bit clear.
+type BitLayout struct {
+	TimestampBits uint8
+	NodeBits      uint8
+	SeqBits       uint8
+}
+
+// DefaultLayout is the layout used by NewSnowflakeNode.
+var DefaultLayout = BitLayout{
+	TimestampBits: 63 - baseNodeBits - baseSeqIdBits,
+	NodeBits:      baseNodeBits,
+	SeqBits:       baseSeqIdBits,
+}
+
+// Validate checks that l describes a 63-bit layout.
+func (l BitLayout) Validate() error {
+	if int(l.TimestampBits)+int(l.NodeBits)+int(l.SeqBits) != 63 {
+		return ErrInvalidLayout
+	}
+	return nil
+}
+
+func (l BitLayout) unpack(sf Snowflake) (timestamp, nodeId, sequence int64) {
+	timestamp = int64(sf) >> (l.NodeBits + l.SeqBits)
+	nodeId = (int64(sf) >> l.SeqBits) & (1<<l.NodeBits - 1)
+	sequence = int64(sf) & (1<<l.SeqBits - 1)
+	return timestamp, nodeId, sequence
+}
+
+// Pack builds a snowflake from its fields, returning an error if the
+// layout is invalid or a field does not fit its width.
+func (l BitLayout) Pack(timestamp, nodeId, sequence int64) (Snowflake, error) {
+	if err := l.Validate(); err != nil {
+		return 0, err
+	}
+	if timestamp < 0 || timestamp >= 1<<l.TimestampBits {
+		return 0, ErrTimestampOverflow
+	}
+	if nodeId < 0 || nodeId >= 1<<l.NodeBits {
+		return 0, ErrNodeIDOutOfRange
+	}
+	if sequence < 0 || sequence >= 1<<l.SeqBits {
+		return 0, ErrSequenceOutOfRange
+	}
+	return Snowflake(timestamp<<(l.NodeBits+l.SeqBits) | nodeId<<l.SeqBits | sequence), nil
+}
+
+// Repack re-encodes sf, decoded with the from layout, using the to
+// layout, e.g. when migrating to a narrower node field. It fails if a
+// field does not fit its new width.
+func (sf Snowflake) Repack(from, to BitLayout) (Snowflake, error) {
+	if err := from.Validate(); err != nil {
+		return 0, err
+	}
+	return to.Pack(from.unpack(sf))
+}
+
+// SnowflakeNodeInfo describes the configuration a node generates IDs
+// with.
+type SnowflakeNodeInfo struct {
+	BitLayout
+	NodeID int64
+	Epoch  time.Time
+
+	// TimeUnit is the duration of one step of the timestamp field; zero
+	// means a millisecond.
+	TimeUnit time.Duration
+}
+
+// Info returns the configuration of the node. The fields it reports are
+// fixed at construction, so no locking is needed.
+func (self *SnowflakeNode) Info() SnowflakeNodeInfo {
+	return SnowflakeNodeInfo{
+		BitLayout: BitLayout{
+			TimestampBits: self.epochBits,
+			NodeBits:      self.nodeIdBits,
+			SeqBits:       self.seqIdBits,
+		},
+		NodeID:   self.nodeId,
+		Epoch:    self.epoch,
+		TimeUnit: self.timeUnit,
+	}
+}
+
+// String describes the node's configuration, e.g.
+// "SnowflakeNode{id=5 bits=41|10|12 epoch=2021-01-21}", for logs. Like
+// Info it only reads fixed fields, so it does not take the lock.
+func (self *SnowflakeNode) String() string {
+	return fmt.Sprintf("SnowflakeNode{id=%d bits=%d|%d|%d epoch=%s}",
+		self.nodeId, self.epochBits, self.nodeIdBits, self.seqIdBits,
+		self.epoch.UTC().Format("2006-01-02"))
+}
+
+// NodeID returns the node ID the node writes into its IDs, after any
+// masking by NewSnowflakeNode. It is fixed at construction, so no
+// locking is needed.
+func (self *SnowflakeNode) NodeID() int64 {
+	return self.nodeId
+}
+
+// ShardID is an alias for NodeID.
+func (self *SnowflakeNode) ShardID() int64 {
+	return self.NodeID()
+}
+
+// TimeResolution returns the duration of one step of the node's
+// timestamp field. IDs generated within the same step are ordered by
+// sequence only, so IDs from different nodes within one step have no
+// meaningful order relative to each other.
+func (self *SnowflakeNode) TimeResolution() time.Duration {
+	return self.timeUnit
+}
+
+// ExpectedExpiryTime returns when the node's timestamp field overflows,
+// i.e. the epoch plus one time unit for each value of the field. With
+// the default layout that is in 2090.
+func (self *SnowflakeNode) ExpectedExpiryTime() time.Time {
+	// The span overflows a Duration for wide fields or coarse units
+	hi, lo := bits.Mul64(uint64(1)<<self.epochBits, uint64(self.timeUnit))
+	if hi >= uint64(time.Second)>>2 {
+		// Far beyond anything a time.Time can usefully represent
+		return time.Unix(math.MaxInt64>>2, 0)
+	}
+	sec, nsec := bits.Div64(hi, lo, uint64(time.Second))
+	return time.Unix(self.epoch.Unix()+int64(sec), int64(self.epoch.Nanosecond())+int64(nsec))
+}
+
+// Decompose splits sf into its timestamp, node and sequence fields using
+// the bit widths of cfg. If cfg is not a valid layout all three values
+// are -1.
+func (sf Snowflake) Decompose(cfg SnowflakeNodeInfo) (timestamp, nodeId, sequence int64) {
+	if cfg.Validate() != nil {
+		return -1, -1, -1
+	}
+	return cfg.unpack(sf)
+}
+
+// HumanReadable describes sf for diagnostics, decoded with the layout,
+// epoch and time unit of cfg, e.g.
+// "sf=9876543210 time=2024-01-15T10:30:45.123Z node=5 seq=42". The time
+// is the wall-clock time in UTC, in RFC 3339 with milliseconds. If cfg
+// is not a valid layout only the raw value is shown.
+func (sf Snowflake) HumanReadable(cfg SnowflakeNodeInfo) string {
+	if cfg.Validate() != nil {
+		return fmt.Sprintf("sf=%d layout=invalid", int64(sf))
+	}
+	ts, node, seq := cfg.unpack(sf)
+	unit := cfg.TimeUnit
+	if unit <= 0 {
+		unit = time.Millisecond
+	}
+	t := cfg.Epoch.Add(time.Duration(ts) * unit).UTC()
+	return fmt.Sprintf("sf=%d time=%s node=%d seq=%d",
+		int64(sf), t.Format("2006-01-02T15:04:05.000Z07:00"), node, seq)
+}
+
+// IsConsistentWith reports whether sf and other could come from the
+// same node within maxDelta of each other: decoded with nodeBits for the
+// node field and seqBits for the sequence (the timestamp taking the rest,
+// in milliseconds), they have the same node ID and timestamps less than
+// maxDelta apart. IDs from one node ID far apart in time, or in a change
+// stream that should be local, may mean two nodes share the ID. It is
+// false for an invalid layout or negative IDs.
+func (sf Snowflake) IsConsistentWith(other Snowflake, nodeBits, seqBits uint8, maxDelta time.Duration) bool {
+	if nodeBits == 0 || seqBits == 0 || int(nodeBits)+int(seqBits) > 63-minEpochBits || sf < 0 || other < 0 {
+		return false
+	}
+	l := BitLayout{TimestampBits: 63 - nodeBits - seqBits, NodeBits: nodeBits, SeqBits: seqBits}
+	ts1, node1, _ := l.unpack(sf)
+	ts2, node2, _ := l.unpack(other)
+	if node1 != node2 {
+		return false
+	}
+	delta := ts1 - ts2
+	if delta < 0 {
+		delta = -delta
+	}
+	// Wide timestamp fields can overflow a Duration
+	if delta > math.MaxInt64/int64(time.Millisecond) {
+		return false
+	}
+	return time.Duration(delta)*time.Millisecond < maxDelta
+}
+
+// CompiledLayout holds the shifts and masks for a BitLayout so that
+// decoding many IDs does not recompute them. Build one with Compile.
+type CompiledLayout struct {
+	timeShift uint8
+	nodeShift uint8
+	nodeMask  int64
+	seqMask   int64
+}
+
+// Compile precomputes the shifts and masks of l. It does not validate
+// l; check Validate first if it comes from configuration.
+func (l BitLayout) Compile() CompiledLayout {
+	return CompiledLayout{
+		timeShift: l.NodeBits + l.SeqBits,
+		nodeShift: l.SeqBits,
+		nodeMask:  1<<l.NodeBits - 1,
+		seqMask:   1<<l.SeqBits - 1,
+	}
+}
+
+// Time returns the timestamp field of sf.
+func (c CompiledLayout) Time(sf Snowflake) int64 {
+	return int64(sf) >> c.timeShift
+}
+
+// Node returns the node field of sf.
+func (c CompiledLayout) Node(sf Snowflake) int64 {
+	return (int64(sf) >> c.nodeShift) & c.nodeMask
+}
+
+// Seq returns the sequence field of sf.
+func (c CompiledLayout) Seq(sf Snowflake) int64 {
+	return int64(sf) & c.seqMask
+}
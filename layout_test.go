@@ -0,0 +1,164 @@
+package snowflake
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDecompose(t *testing.T) {
+	node, _ := NewSnowflakeNodeWithBits(9, 8, 14)
+	sf := node.Next()
+
+	_, nodeId, _ := sf.Decompose(node.Info())
+	if nodeId != 9 {
+		t.Errorf("(1) Decomposed node ID %d, expected 9!", nodeId)
+	}
+
+	sf = Snowflake(1000<<22 | 5<<12 | 3)
+	ts, nodeId, seq := sf.Decompose(NewSnowflakeNode(5).Info())
+	if ts != 1000 || nodeId != 5 || seq != 3 {
+		t.Errorf("(2) Decomposed %d into %d/%d/%d, expected 1000/5/3!", sf, ts, nodeId, seq)
+	}
+
+	bad := SnowflakeNodeInfo{BitLayout: BitLayout{TimestampBits: 41, NodeBits: 10, SeqBits: 10}}
+	if ts, _, _ := sf.Decompose(bad); ts != -1 {
+		t.Errorf("(3) Invalid layout was not rejected!")
+	}
+}
+
+func TestHumanReadable(t *testing.T) {
+	// 2024-01-15T10:30:45.123Z, 94,062,645,123ms after the epoch
+	sf := Snowflake(94062645123<<22 | 5<<12 | 42)
+	expected := fmt.Sprintf("sf=%d time=2024-01-15T10:30:45.123Z node=5 seq=42", int64(sf))
+	if s := sf.HumanReadable(NewSnowflakeNode(5).Info()); s != expected {
+		t.Errorf("(1) Expected %q, got %q!", expected, s)
+	}
+
+	node, _ := New(5, WithBits(10, 14), WithTimeUnit(10*time.Millisecond))
+	sf = Snowflake(9406264512<<24 | 5<<14 | 42)
+	if s := sf.HumanReadable(node.Info()); s != fmt.Sprintf("sf=%d time=2024-01-15T10:30:45.120Z node=5 seq=42", int64(sf)) {
+		t.Errorf("(2) Node with a 10ms unit gave %q!", s)
+	}
+
+	bad := SnowflakeNodeInfo{BitLayout: BitLayout{TimestampBits: 41, NodeBits: 10, SeqBits: 10}}
+	if s := sf.HumanReadable(bad); s != fmt.Sprintf("sf=%d layout=invalid", int64(sf)) {
+		t.Errorf("(3) Invalid layout gave %q!", s)
+	}
+}
+
+func TestIsConsistentWith(t *testing.T) {
+	a := Snowflake(1000<<22 | 5<<12 | 1)
+	if !a.IsConsistentWith(Snowflake(1000<<22|5<<12|2), 10, 12, time.Millisecond) {
+		t.Errorf("(1) IDs from one node in one millisecond are inconsistent!")
+	}
+	later := Snowflake(1500<<22 | 5<<12 | 0)
+	if !a.IsConsistentWith(later, 10, 12, time.Second) || later.IsConsistentWith(a, 10, 12, 500*time.Millisecond) {
+		t.Errorf("(2) IDs 500ms apart were not checked against maxDelta!")
+	}
+	if a.IsConsistentWith(Snowflake(1000<<22|6<<12|1), 10, 12, time.Hour) {
+		t.Errorf("(3) IDs from nodes 5 and 6 are consistent!")
+	}
+	// Nodes 5 and 6 look alike with an 8-bit node field over 14 bits of sequence
+	if !Snowflake(1000<<22|5<<12).IsConsistentWith(Snowflake(1000<<22|6<<12), 8, 14, time.Millisecond) {
+		t.Errorf("(4) Layout was not applied!")
+	}
+	if a.IsConsistentWith(a, 0, 12, time.Hour) || a.IsConsistentWith(-1, 10, 12, time.Hour) {
+		t.Errorf("(5) Invalid input is consistent!")
+	}
+}
+
+func TestNodeID(t *testing.T) {
+	node := NewSnowflakeNode(42)
+	if node.NodeID() != 42 || node.ShardID() != 42 {
+		t.Errorf("(1) Expected node ID 42, got %d and %d!", node.NodeID(), node.ShardID())
+	}
+	if _, id, _ := node.Next().parts(); id != node.NodeID() {
+		t.Errorf("(2) ID carries node %d, not %d!", id, node.NodeID())
+	}
+
+	wide := NewSnowflakeNode(5000)
+	sf := wide.Next()
+	if wide.NodeID() != 904 || sf.Node() != 904 {
+		t.Errorf("(3) Node ID 5000 was not masked to 904, got %d in %d!", wide.NodeID(), sf)
+	}
+	if age := sf.Age(); age < 0 || age > time.Second {
+		t.Errorf("(4) Wide node ID corrupted the timestamp, age %v!", age)
+	}
+	if n := NewSnowflakeNode(-1); n.NodeID() != 1023 {
+		t.Errorf("(5) Node ID -1 was not masked to 1023, got %d!", n.NodeID())
+	}
+}
+
+func TestSnowflakeNodeString(t *testing.T) {
+	l := &countingLocker{}
+	node, _ := New(5, WithLocker(l))
+	if s := node.String(); s != "SnowflakeNode{id=5 bits=41|10|12 epoch=2021-01-21}" {
+		t.Errorf("(1) Unexpected node description %q!", s)
+	}
+	if s := fmt.Sprint(node); s != node.String() {
+		t.Errorf("(2) fmt does not use String, got %q!", s)
+	}
+	if l.locks != 0 {
+		t.Errorf("(3) String took the node's lock %d times!", l.locks)
+	}
+}
+
+func TestTimeResolution(t *testing.T) {
+	if r := NewSnowflakeNode(1).TimeResolution(); r != time.Millisecond {
+		t.Errorf("(1) Expected millisecond resolution, got %v!", r)
+	}
+}
+
+func TestRepack(t *testing.T) {
+	wide := BitLayout{TimestampBits: 42, NodeBits: 8, SeqBits: 13}
+	sf := Snowflake(1000<<22 | 200<<12 | 4000)
+
+	moved, err := sf.Repack(DefaultLayout, wide)
+	if err != nil {
+		t.Fatalf("(1) Could not repack %d: %v", sf, err)
+	}
+	if ts, nodeId, seq := wide.unpack(moved); ts != 1000 || nodeId != 200 || seq != 4000 {
+		t.Errorf("(2) Repacked fields are %d/%d/%d, expected 1000/200/4000!", ts, nodeId, seq)
+	}
+
+	back, err := moved.Repack(wide, DefaultLayout)
+	if err != nil || back != sf {
+		t.Errorf("(3) Round trip returned %d, %v, expected %d!", back, err, sf)
+	}
+
+	if _, err := Snowflake(1000<<22|300<<12).Repack(DefaultLayout, wide); err != ErrNodeIDOutOfRange {
+		t.Errorf("(4) Node 300 fit in 8 bits? Got %v!", err)
+	}
+
+	if _, err := moved.Repack(wide, BitLayout{TimestampBits: 42, NodeBits: 8, SeqBits: 12}); err != ErrInvalidLayout {
+		t.Errorf("(5) Invalid target layout was not rejected, got %v!", err)
+	}
+}
+
+func TestCompiledLayout(t *testing.T) {
+	wide := BitLayout{TimestampBits: 42, NodeBits: 8, SeqBits: 13}
+	c := wide.Compile()
+	sf, _ := wide.Pack(1000, 200, 4000)
+	if c.Time(sf) != 1000 || c.Node(sf) != 200 || c.Seq(sf) != 4000 {
+		t.Errorf("(1) Compiled layout decoded %d as %d/%d/%d!", sf, c.Time(sf), c.Node(sf), c.Seq(sf))
+	}
+}
+
+var layoutSink int64
+
+func BenchmarkDecodeLayout(b *testing.B) {
+	cfg := SnowflakeNodeInfo{BitLayout: BitLayout{TimestampBits: 42, NodeBits: 8, SeqBits: 13}}
+	for i := 0; i < b.N; i++ {
+		ts, node, seq := Snowflake(i).Decompose(cfg)
+		layoutSink += ts + node + seq
+	}
+}
+
+func BenchmarkDecodeCompiledLayout(b *testing.B) {
+	c := BitLayout{TimestampBits: 42, NodeBits: 8, SeqBits: 13}.Compile()
+	for i := 0; i < b.N; i++ {
+		sf := Snowflake(i)
+		layoutSink += c.Time(sf) + c.Node(sf) + c.Seq(sf)
+	}
+}
@@ -0,0 +1,35 @@
+package snowflake
+
+import "time"
+
+// SnowflakeMetrics receives a node's generation events, so that they can
+// be exported to a metrics system such as Prometheus without this
+// package depending on it. Methods are called with the node's lock held,
+// so they must be quick, safe for concurrent use, and must not call the
+// node's methods.
+type SnowflakeMetrics interface {
+	// IncrGenerated is called for each ID issued by Next, NextE,
+	// NextNonBlocking and the batch methods other than ReserveBlock.
+	IncrGenerated()
+	// IncrExhausted is called each time a millisecond's sequence space
+	// runs out, as counted by DriftReport.
+	IncrExhausted()
+	// ObserveDrift is called with how far behind the last ID issued the
+	// clock read, once for each drift event in DriftReport.
+	ObserveDrift(d time.Duration)
+}
+
+// NopMetrics is a SnowflakeMetrics that ignores every event.
+type NopMetrics struct{}
+
+func (NopMetrics) IncrGenerated()             {}
+func (NopMetrics) IncrExhausted()             {}
+func (NopMetrics) ObserveDrift(time.Duration) {}
+
+// WithMetrics reports the node's generation events to m.
+func WithMetrics(m SnowflakeMetrics) Option {
+	return func(node *SnowflakeNode) error {
+		node.stats.metrics = m
+		return nil
+	}
+}
@@ -0,0 +1,48 @@
+package snowflake
+
+import (
+	"testing"
+	"time"
+)
+
+type countingMetrics struct {
+	generated, exhausted int
+	drifts               []time.Duration
+}
+
+func (m *countingMetrics) IncrGenerated()               { m.generated++ }
+func (m *countingMetrics) IncrExhausted()               { m.exhausted++ }
+func (m *countingMetrics) ObserveDrift(d time.Duration) { m.drifts = append(m.drifts, d) }
+
+func TestWithMetrics(t *testing.T) {
+	now := time.Now()
+	m := &countingMetrics{}
+	node, _ := New(1,
+		WithClock(func() time.Time { return now }),
+		WithExhaustionStrategy(steppingStrategy{now: &now}),
+		WithMonotonicClock(),
+		WithMetrics(m))
+
+	for i := 0; i < 10; i++ {
+		node.Next()
+	}
+	if m.generated != 10 || m.exhausted != 0 || len(m.drifts) != 0 {
+		t.Errorf("(1) Expected 10 IDs and nothing else, got %+v!", m)
+	}
+
+	node.NextN(4096)
+	if m.generated != 4106 || m.exhausted != 1 {
+		t.Errorf("(2) Expected 4106 IDs and one exhaustion, got %+v!", m)
+	}
+
+	now = now.Add(-5 * time.Millisecond)
+	node.Next()
+	if len(m.drifts) != 1 || m.drifts[0] != 5*time.Millisecond {
+		t.Errorf("(3) Expected one drift of 5ms, got %v!", m.drifts)
+	}
+
+	var nop SnowflakeMetrics = NopMetrics{}
+	if node, err := New(1, WithMetrics(nop)); err != nil || node.Next() < 0 {
+		t.Errorf("(4) NopMetrics node failed: %v!", err)
+	}
+}
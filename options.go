@@ -0,0 +1,186 @@
+package snowflake
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// An Option configures a node created by New.
+type Option func(*SnowflakeNode) error
+
+// ErrConflictingOptions is returned by New when given options that
+// cannot be combined.
+var ErrConflictingOptions = errors.New("snowflake: conflicting options")
+
+// New returns a node for shardId with the default layout and epoch,
+// adjusted by opts, which are applied in order. Unlike NewSnowflakeNode,
+// shardId must fit the node field of the resulting layout; it is checked
+// once the options have set the layout, before any validator from
+// WithNodeIDValidator runs.
+func New(shardId int, opts ...Option) (*SnowflakeNode, error) {
+	node := newSnowflakeNode(int64(shardId), baseNodeBits, baseSeqIdBits)
+	for _, opt := range opts {
+		if err := opt(node); err != nil {
+			return nil, err
+		}
+	}
+	if shardId < 0 || int64(shardId) >= 1<<node.nodeIdBits {
+		return nil, ErrNodeIDOutOfRange
+	}
+	for _, validate := range node.validators {
+		if err := validate(node.nodeId); err != nil {
+			return nil, err
+		}
+	}
+	node.validators = nil
+	if err := node.publishExpvar(); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// WithEpoch makes the node count time from epochMs, in Unix
+// milliseconds, rather than the default epoch. Snowflake methods that
+// read the time assume the default epoch; decode the node's IDs with
+// AtTime or the node's Info instead. It cannot be combined with
+// WithEpochTime.
+func WithEpoch(epochMs int64) Option {
+	return func(node *SnowflakeNode) error {
+		return node.setEpoch(epochMs, "WithEpoch")
+	}
+}
+
+// WithEpochTime is WithEpoch taking the epoch as a time.Time, truncated
+// to the millisecond. It cannot be combined with WithEpoch.
+func WithEpochTime(t time.Time) Option {
+	return func(node *SnowflakeNode) error {
+		return node.setEpoch(t.UnixMilli(), "WithEpochTime")
+	}
+}
+
+func (self *SnowflakeNode) setEpoch(epochMs int64, option string) error {
+	if self.epochOption != "" && self.epochOption != option {
+		return fmt.Errorf("%w: %s and %s", ErrConflictingOptions, self.epochOption, option)
+	}
+	self.epochOption = option
+	now := time.Now()
+	self.epoch = now.Add(time.UnixMilli(epochMs).Sub(now))
+	return nil
+}
+
+// WithBits gives the node nodeBits for the node field and seqBits for the
+// sequence, as NewSnowflakeNodeWithBits does; the timestamp takes the
+// remaining bits. Decode the node's IDs with Decompose and its Info.
+func WithBits(nodeBits, seqBits uint8) Option {
+	return func(node *SnowflakeNode) error {
+		if nodeBits == 0 || seqBits == 0 || int(nodeBits)+int(seqBits) > 63-minEpochBits {
+			return ErrInvalidLayout
+		}
+		node.epochBits = 63 - nodeBits - seqBits
+		node.nodeIdBits = nodeBits
+		node.seqIdBits = seqBits
+		node.seqStep = -1 ^ (-1 << seqBits)
+		node.timeStep = nodeBits + seqBits
+		node.nodeStep = seqBits
+		return nil
+	}
+}
+
+// WithTimeUnit makes one step of the node's timestamp field last unit
+// rather than a millisecond, so the timestamp counts units of unit since
+// the epoch. A coarser unit suits nodes that can do with fewer, larger
+// steps, e.g. 10ms with WithBits(10, 14) gives 16384 IDs per step and
+// still outlasts the default layout. Sequence capacity, WithRateLimit
+// and NextWithTimestamp then apply per unit. Snowflake methods that read
+// the time assume milliseconds; scale the timestamp field by the node's
+// TimeResolution instead.
+func WithTimeUnit(unit time.Duration) Option {
+	return func(node *SnowflakeNode) error {
+		if unit <= 0 {
+			return ErrInvalidLayout
+		}
+		node.timeUnit = unit
+		return nil
+	}
+}
+
+// WithRateLimit caps the node at perMilli IDs per millisecond, below
+// the sequence capacity, waiting for the next millisecond (or failing,
+// as for exhaustion) once a millisecond's allowance is used up. It
+// applies to Next, NextE, NextNonBlocking and the batch methods other
+// than ReserveBlock, and not to secure nodes.
+func WithRateLimit(perMilli int64) Option {
+	return func(node *SnowflakeNode) error {
+		if perMilli < 1 {
+			return ErrSequenceOutOfRange
+		}
+		node.rateLimit = perMilli
+		return nil
+	}
+}
+
+// WithObserver calls observe with every ID returned by Next, NextE and
+// NextNonBlocking, after the node's lock is released, e.g. to record
+// IDs for auditing. It slows generation by however long observe takes.
+func WithObserver(observe func(sf Snowflake)) Option {
+	return func(node *SnowflakeNode) error {
+		node.observer = observe
+		return nil
+	}
+}
+
+// WithLocker replaces the node's internal mutex with l, e.g. to use a
+// lock instrumented for tracing. Next acquires l exactly once per call.
+func WithLocker(l sync.Locker) Option {
+	return func(node *SnowflakeNode) error {
+		node.mutex = l
+		return nil
+	}
+}
+
+// WithClock makes the node read the time from clock instead of
+// time.Now, e.g. to control time in tests.
+func WithClock(clock func() time.Time) Option {
+	return func(node *SnowflakeNode) error {
+		node.clock = clock
+		return nil
+	}
+}
+
+// WithRetryOnExhaustion bounds how long the node waits for the clock
+// when the sequence space is used up or the clock moved backwards.
+// Once maxWait has passed NextE fails with ErrSequenceExhausted (or
+// ErrClockBackwards) and Next returns -1. Zero, the default, waits
+// forever.
+func WithRetryOnExhaustion(maxWait time.Duration) Option {
+	return func(node *SnowflakeNode) error {
+		node.maxWait = maxWait
+		return nil
+	}
+}
+
+// WithMonotonicClock makes the node keep a logical clock that never goes
+// backwards: if the clock reads earlier than the last ID issued (e.g.
+// after an NTP step of an injected clock), the node keeps counting in the
+// last millisecond, stepping it forward when the sequence runs out,
+// instead of waiting for the clock to catch up. IDs then run ahead of the
+// clock until it does; see Drift.
+func WithMonotonicClock() Option {
+	return func(node *SnowflakeNode) error {
+		node.monotonic = true
+		return nil
+	}
+}
+
+// WithNodeIDValidator calls validate with the node ID once, while the
+// node is constructed, e.g. to check it against a registry of assigned
+// IDs. It only runs once the node ID is known to fit. If validate fails,
+// New returns its error.
+func WithNodeIDValidator(validate func(nodeId int64) error) Option {
+	return func(node *SnowflakeNode) error {
+		node.validators = append(node.validators, validate)
+		return nil
+	}
+}
@@ -0,0 +1,257 @@
+package snowflake
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type countingLocker struct {
+	sync.Mutex
+	locks int
+}
+
+func (l *countingLocker) Lock() {
+	l.Mutex.Lock()
+	l.locks++
+}
+
+func TestWithLocker(t *testing.T) {
+	for _, l := range []sync.Locker{&sync.Mutex{}, &sync.RWMutex{}} {
+		node, err := New(2, WithLocker(l))
+		if err != nil {
+			t.Fatalf("(1) Could not create node: %v", err)
+		}
+		if a, b := node.Next(), node.Next(); a >= b {
+			t.Errorf("(2) IDs %d and %d from %T locker are not increasing!", a, b, l)
+		}
+	}
+
+	l := &countingLocker{}
+	node, _ := New(2, WithLocker(l))
+	for i := 0; i < 10; i++ {
+		node.Next()
+	}
+	if l.locks != 10 {
+		t.Errorf("(3) Expected 10 lock acquisitions for 10 IDs, got %d!", l.locks)
+	}
+
+	sf, str := node.NextPair()
+	if str != sf.String() || sf <= 0 || l.locks != 11 {
+		t.Errorf("(4) NextPair returned %d and %q with %d locks!", sf, str, l.locks)
+	}
+}
+
+func TestWithRetryOnExhaustion(t *testing.T) {
+	frozen := time.Now()
+	maxWait := 20 * time.Millisecond
+	node, _ := New(1,
+		WithClock(func() time.Time { return frozen }),
+		WithRetryOnExhaustion(maxWait))
+
+	for i := 0; i < 4096; i++ {
+		if _, err := node.NextE(); err != nil {
+			t.Fatalf("(1) ID %d failed with %v!", i, err)
+		}
+	}
+
+	start := time.Now()
+	if _, err := node.NextE(); err != ErrSequenceExhausted {
+		t.Errorf("(2) Expected ErrSequenceExhausted, got %v!", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*maxWait {
+		t.Errorf("(3) Gave up after %v, expected at most %v!", elapsed, 2*maxWait)
+	}
+	if sf := node.Next(); sf != -1 {
+		t.Errorf("(4) Next on a frozen clock returned %d, expected -1!", sf)
+	}
+}
+
+func TestClockBackwards(t *testing.T) {
+	now := time.Now()
+	node, _ := New(1,
+		WithClock(func() time.Time { return now }),
+		WithRetryOnExhaustion(5*time.Millisecond))
+
+	first := node.Next()
+	now = now.Add(-time.Second)
+	if _, err := node.NextNonBlocking(); err != ErrClockBackwards {
+		t.Errorf("(1) Expected ErrClockBackwards, got %v!", err)
+	}
+	if _, err := node.NextE(); err != ErrClockBackwards {
+		t.Errorf("(2) Expected ErrClockBackwards after waiting, got %v!", err)
+	}
+
+	now = now.Add(2 * time.Second)
+	if sf := node.Next(); sf <= first {
+		t.Errorf("(3) ID %d after the clock recovered is not after %d!", sf, first)
+	}
+}
+
+func TestDrift(t *testing.T) {
+	if d := NewSnowflakeNode(1).Drift(); d < -10*time.Millisecond || d > 10*time.Millisecond {
+		t.Errorf("(1) Fresh node has drift %v!", d)
+	}
+
+	ahead := time.Now().Add(time.Hour)
+	node, _ := New(1, WithClock(func() time.Time { return ahead }))
+	if d := node.Drift(); d < 59*time.Minute || d > 61*time.Minute {
+		t.Errorf("(2) Node an hour ahead has drift %v!", d)
+	}
+}
+
+func TestWithMonotonicClock(t *testing.T) {
+	now := time.Now()
+	node, _ := New(1,
+		WithClock(func() time.Time { return now }),
+		WithMonotonicClock())
+
+	last := node.Next()
+	now = now.Add(-time.Second)
+	for i := 0; i < 5000; i++ {
+		sf, err := node.NextNonBlocking()
+		if err != nil {
+			t.Fatalf("(1) Monotonic node failed with %v!", err)
+		}
+		if sf <= last {
+			t.Fatalf("(2) ID %d is not after %d!", sf, last)
+		}
+		last = sf
+	}
+}
+
+func TestWithNodeIDValidator(t *testing.T) {
+	var seen []int64
+	valid := func(id int64) error {
+		seen = append(seen, id)
+		return nil
+	}
+	node, err := New(7, WithNodeIDValidator(valid))
+	if err != nil {
+		t.Fatalf("(1) Could not create node: %v", err)
+	}
+	node.Next()
+	node.Next()
+	if len(seen) != 1 || seen[0] != 7 {
+		t.Errorf("(2) Expected validator to be called once with 7, got %v!", seen)
+	}
+
+	errUnregistered := errors.New("node not registered")
+	node, err = New(8, WithNodeIDValidator(func(int64) error {
+		return errUnregistered
+	}))
+	if err != errUnregistered || node != nil {
+		t.Errorf("(3) Expected validator error, got %v, %v!", node, err)
+	}
+
+	called := false
+	_, err = New(5000, WithNodeIDValidator(func(int64) error {
+		called = true
+		return nil
+	}))
+	if err != ErrNodeIDOutOfRange || called {
+		t.Errorf("(4) Out of range node ID returned %v, validator called: %v!", err, called)
+	}
+}
+
+func TestNew(t *testing.T) {
+	epoch := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := epoch.Add(time.Hour)
+	var observed []Snowflake
+	node, err := New(300,
+		WithEpoch(epoch.UnixMilli()),
+		WithBits(9, 13),
+		WithClock(func() time.Time { return now }),
+		WithObserver(func(sf Snowflake) { observed = append(observed, sf) }))
+	if err != nil {
+		t.Fatalf("(1) Could not create node: %v", err)
+	}
+	sf := node.Next()
+	if ms, id, seq := sf.Decompose(node.Info()); ms != time.Hour.Milliseconds() || id != 300 || seq != 0 {
+		t.Errorf("(2) ID %d decomposes to %d, %d, %d!", sf, ms, id, seq)
+	}
+	if len(observed) != 1 || observed[0] != sf {
+		t.Errorf("(3) Observer saw %v, expected [%d]!", observed, sf)
+	}
+
+	if _, err := New(600, WithBits(9, 13)); err != ErrNodeIDOutOfRange {
+		t.Errorf("(4) Node ID too wide for 9 bits was not rejected, got %v!", err)
+	}
+	if _, err := New(1, WithBits(20, 20)); err != ErrInvalidLayout {
+		t.Errorf("(5) Invalid layout was not rejected, got %v!", err)
+	}
+}
+
+func TestWithRateLimit(t *testing.T) {
+	frozen := time.Now()
+	node, _ := New(1,
+		WithClock(func() time.Time { return frozen }),
+		WithRateLimit(10))
+	for i := 0; i < 10; i++ {
+		if _, err := node.NextNonBlocking(); err != nil {
+			t.Fatalf("(1) ID %d failed with %v!", i, err)
+		}
+	}
+	if _, err := node.NextNonBlocking(); err != ErrExhausted {
+		t.Errorf("(2) Expected ErrExhausted past the limit, got %v!", err)
+	}
+
+	frozen = frozen.Add(time.Millisecond)
+	if sf, err := node.NextNonBlocking(); err != nil || sf.Sequence() != 0 {
+		t.Errorf("(3) Expected sequence 0 in the next millisecond, got %d, %v!", sf, err)
+	}
+	if _, err := New(1, WithRateLimit(0)); err != ErrSequenceOutOfRange {
+		t.Errorf("(4) Zero rate limit was not rejected, got %v!", err)
+	}
+}
+
+func TestWithEpochTime(t *testing.T) {
+	now := time.Now()
+	clock := WithClock(func() time.Time { return now })
+	byDefault, _ := New(3, clock)
+	byTime, err := New(3, clock, WithEpochTime(time.UnixMilli(baseEpoch)))
+	if err != nil {
+		t.Fatalf("(1) Could not create node: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if a, b := byDefault.Next(), byTime.Next(); a != b {
+			t.Errorf("(2) IDs %d and %d from the same epoch differ!", a, b)
+		}
+	}
+
+	if _, err := New(3, WithEpoch(baseEpoch), WithEpochTime(time.UnixMilli(baseEpoch))); !errors.Is(err, ErrConflictingOptions) {
+		t.Errorf("(3) Conflicting epoch options returned %v!", err)
+	}
+}
+
+func TestWithTimeUnit(t *testing.T) {
+	now := time.UnixMilli(baseEpoch + 12345)
+	node, err := New(3, WithClock(func() time.Time { return now }), WithBits(10, 14), WithTimeUnit(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("(1) Could not create node: %v", err)
+	}
+	if r := node.TimeResolution(); r != 10*time.Millisecond {
+		t.Errorf("(2) Expected 10ms resolution, got %v!", r)
+	}
+	for i := int64(0); i < 1<<14; i++ {
+		sf := node.Next()
+		if ts, _, seq := sf.Decompose(node.Info()); ts != 1234 || seq != i {
+			t.Fatalf("(3) ID %d has timestamp %d and sequence %d!", sf, ts, seq)
+		}
+	}
+	if _, err := node.NextNonBlocking(); err != ErrExhausted {
+		t.Errorf("(4) Expected ErrExhausted after 16384 IDs, got %v!", err)
+	}
+
+	expiry := time.UnixMilli(baseEpoch).Add(time.Duration(1<<39) * 10 * time.Millisecond)
+	if e := node.ExpectedExpiryTime(); !e.Equal(expiry) {
+		t.Errorf("(5) Expected expiry at %v, got %v!", expiry, e)
+	}
+	if e := NewSnowflakeNode(3).ExpectedExpiryTime(); !e.Equal(time.UnixMilli(baseEpoch + 1<<41)) {
+		t.Errorf("(6) Default layout expires at %v!", e)
+	}
+	if _, err := New(3, WithTimeUnit(0)); err != ErrInvalidLayout {
+		t.Errorf("(7) Zero time unit was not rejected, got %v!", err)
+	}
+}
@@ -0,0 +1,175 @@
+package snowflake
+
+import (
+	"fmt"
+	"sync"
+)
+
+// A SnowflakePool spreads generation across several nodes, which share
+// one configuration but have different node IDs, to go beyond the
+// sequence capacity of a single node. Its methods are safe for
+// concurrent use.
+type SnowflakePool struct {
+	mutex  sync.Mutex
+	nodes  []*SnowflakeNode // every node created, active ones first
+	active int
+	next   int
+	last   *SnowflakeNode
+
+	target   float64
+	spareIDs []int
+
+	// IDs issued by the pool in millisecond ms.
+	ms    int64
+	count int64
+}
+
+// AutoScale returns a pool that starts out with just the node and adds
+// nodes, with node IDs taken in order from the inclusive range
+// nodeIdRange (skipping the node's own), whenever the node that issued
+// an ID has used more than targetUtilization of its millisecond's
+// sequence space (see SequenceUtilization). When the pool as a whole
+// used less than half of targetUtilization of its capacity in the
+// previous millisecond, it deactivates its newest node. A node that has
+// run out of sequence numbers is skipped for the next active one; the
+// pool only waits for the clock once all of them have. Deactivated
+// nodes are kept and reused, so a node ID never restarts from scratch.
+// The added nodes copy the node's layout, epoch, clock and generation
+// options.
+func (self *SnowflakeNode) AutoScale(targetUtilization float64, nodeIdRange [2]int) (*SnowflakePool, error) {
+	if !(targetUtilization > 0 && targetUtilization <= 1) {
+		return nil, fmt.Errorf("snowflake: target utilization %v not in (0, 1]", targetUtilization)
+	}
+	lo, hi := nodeIdRange[0], nodeIdRange[1]
+	if lo < 0 || hi < lo || int64(hi) >= 1<<self.nodeIdBits {
+		return nil, ErrNodeIDOutOfRange
+	}
+	p := &SnowflakePool{
+		nodes:  []*SnowflakeNode{self},
+		active: 1,
+		target: targetUtilization,
+	}
+	for id := lo; id <= hi; id++ {
+		if int64(id) != self.nodeId {
+			p.spareIDs = append(p.spareIDs, id)
+		}
+	}
+	return p, nil
+}
+
+// sibling returns a fresh node like self but with node ID id.
+func (self *SnowflakeNode) sibling(id int) *SnowflakeNode {
+	node := newSnowflakeNode(int64(id), self.nodeIdBits, self.seqIdBits)
+	node.epochBits = self.epochBits
+	node.epoch = self.epoch
+	node.timeUnit = self.timeUnit
+	node.SpinStrategy = self.SpinStrategy
+	node.clock = self.clock
+	node.maxWait = self.maxWait
+	node.monotonic = self.monotonic
+	node.rateLimit = self.rateLimit
+	node.observer = self.observer
+	node.strategy = self.strategy
+	node.onExhaustion = self.onExhaustion
+	node.stats.metrics = self.stats.metrics
+	node.nano = self.nano
+	if self.secure {
+		node.secure = true
+		node.used = make([]uint64, (node.seqStep+64)/64)
+	}
+	return node
+}
+
+// Next returns a new ID from one of the pool's active nodes, or -1 if it
+// fails (see NextE).
+func (p *SnowflakePool) Next() Snowflake {
+	sf, err := p.NextE()
+	if err != nil {
+		return -1
+	}
+	return sf
+}
+
+// NextE returns a new ID from one of the pool's active nodes, taken in
+// turn, and grows or shrinks the pool as described at AutoScale.
+func (p *SnowflakePool) NextE() (Snowflake, error) {
+	p.mutex.Lock()
+	nodes := p.nodes[:p.active]
+	start := p.next
+	p.next = (p.next + 1) % p.active
+	p.mutex.Unlock()
+
+	// Fail over to the next node rather than wait for one that has run
+	// out, and only wait for the clock once every node has
+	var node *SnowflakeNode
+	var sf Snowflake
+	var err error
+	for i := range nodes {
+		node = nodes[(start+i)%len(nodes)]
+		if sf, err = node.NextNonBlocking(); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		node = nodes[start]
+		if sf, err = node.NextE(); err != nil {
+			return 0, err
+		}
+	}
+	utilization := node.SequenceUtilization()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.last = node
+	if ms := int64(sf) >> node.timeStep & (1<<node.epochBits - 1); ms != p.ms {
+		// A new millisecond: shrink if the last one was quiet, or if
+		// whole milliseconds passed without any IDs
+		capacity := float64(p.active) * float64(node.seqStep+1)
+		if p.count > 0 && (ms > p.ms+1 || float64(p.count)/capacity < p.target/2) {
+			p.shrink()
+		}
+		p.ms, p.count = ms, 0
+	}
+	p.count++
+	if utilization > p.target {
+		p.grow()
+	}
+	return sf, nil
+}
+
+// grow activates one more node, if the node ID range allows.
+func (p *SnowflakePool) grow() {
+	if p.active == len(p.nodes) {
+		if len(p.spareIDs) == 0 {
+			return
+		}
+		p.nodes = append(p.nodes, p.nodes[0].sibling(p.spareIDs[0]))
+		p.spareIDs = p.spareIDs[1:]
+	}
+	// Dispatch to the new node next, since the others are busiest
+	p.next = p.active
+	p.active++
+}
+
+// shrink deactivates the newest active node, keeping at least one.
+func (p *SnowflakePool) shrink() {
+	if p.active > 1 {
+		p.active--
+		p.next %= p.active
+	}
+}
+
+// Size returns the number of active nodes.
+func (p *SnowflakePool) Size() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.active
+}
+
+// LastNode returns the node the pool last dispatched to, or nil if it
+// has not issued an ID yet.
+func (p *SnowflakePool) LastNode() *SnowflakeNode {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.last
+}
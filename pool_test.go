@@ -0,0 +1,73 @@
+package snowflake
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAutoScale(t *testing.T) {
+	now := time.Now()
+	node, _ := New(10, WithClock(func() time.Time { return now }))
+	pool, err := node.AutoScale(0.5, [2]int{10, 12})
+	if err != nil {
+		t.Fatalf("(1) Could not create pool: %v", err)
+	}
+
+	// Half the sequence space is at the target, not above it.
+	for i := 0; i < 2048; i++ {
+		pool.Next()
+	}
+	if pool.Size() != 1 || node.SequenceUtilization() != 0.5 {
+		t.Errorf("(2) Pool grew to %d nodes at utilization %v!", pool.Size(), node.SequenceUtilization())
+	}
+	pool.Next()
+	if pool.Size() != 2 {
+		t.Errorf("(3) Pool did not grow above the target, size %d!", pool.Size())
+	}
+	if sf := pool.Next(); sf.Node() != 11 || pool.LastNode().NodeID() != 11 {
+		t.Errorf("(4) Expected the next ID from node 11, got %d from node %d!", sf, pool.LastNode().NodeID())
+	}
+
+	// 2050 IDs of 8192 is just over half the target: stay.
+	now = now.Add(time.Millisecond)
+	pool.Next()
+	if pool.Size() != 2 {
+		t.Errorf("(5) Pool shrank to %d nodes after a busy millisecond!", pool.Size())
+	}
+	// One ID of 8192 is well below: shrink.
+	now = now.Add(time.Millisecond)
+	pool.Next()
+	if pool.Size() != 1 {
+		t.Errorf("(6) Pool did not shrink after a quiet millisecond, size %d!", pool.Size())
+	}
+
+	if _, err := node.AutoScale(0.5, [2]int{10, 1024}); err != ErrNodeIDOutOfRange {
+		t.Errorf("(7) Out of range node IDs were not rejected, got %v!", err)
+	}
+	if _, err := node.AutoScale(0, [2]int{10, 12}); err == nil {
+		t.Errorf("(8) Zero target utilization was not rejected!")
+	}
+}
+
+func TestAutoScaleRangeExhausted(t *testing.T) {
+	frozen := time.Now()
+	node, _ := New(3, WithClock(func() time.Time { return frozen }))
+	pool, _ := node.AutoScale(0.1, [2]int{3, 4})
+
+	// Exactly the two nodes' capacity: node 3 runs out first and the
+	// pool must fail over to node 4 rather than wait for the frozen clock.
+	seen := make(map[Snowflake]bool)
+	for i := 0; i < 8192; i++ {
+		sf, err := pool.NextE()
+		if err != nil {
+			t.Fatalf("(1) ID %d failed with %v!", i, err)
+		}
+		if seen[sf] {
+			t.Fatalf("(2) Duplicate ID %d!", sf)
+		}
+		seen[sf] = true
+	}
+	if pool.Size() != 2 {
+		t.Errorf("(3) Expected the pool to stop at 2 nodes, got %d!", pool.Size())
+	}
+}
@@ -0,0 +1,96 @@
+package snowflake
+
+import (
+	"encoding/binary"
+	"errors"
+	"strconv"
+)
+
+// ErrInvalidProto is returned when bytes are not a valid encoding of the
+// Snowflake message in snowflake.proto.
+var ErrInvalidProto = errors.New("snowflake: invalid protobuf encoding")
+
+// SnowflakeProtoValue is the Go form of the Snowflake message defined in
+// snowflake.proto. It speaks the proto3 wire format directly, so services
+// can exchange IDs with protobuf peers without this package importing
+// google.golang.org/protobuf. It is not a proto.Message, and the package
+// ships no generated code: generate it with protoc into the separate
+// snowflakepb package named in snowflake.proto, and convert through the
+// generated message's Value field.
+type SnowflakeProtoValue struct {
+	Value Snowflake
+}
+
+// MarshalProto encodes v in proto3 wire format: field 1 as a varint,
+// omitted when zero.
+func (v SnowflakeProtoValue) MarshalProto() ([]byte, error) {
+	if v.Value == 0 {
+		return []byte{}, nil
+	}
+	b := make([]byte, 1+binary.MaxVarintLen64)
+	b[0] = 1<<3 | 0 // field 1, varint
+	n := binary.PutUvarint(b[1:], uint64(v.Value))
+	return b[:1+n], nil
+}
+
+// UnmarshalProto decodes the proto3 wire format, skipping unknown fields
+// as protobuf requires.
+func (v *SnowflakeProtoValue) UnmarshalProto(b []byte) error {
+	var value Snowflake
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return ErrInvalidProto
+		}
+		b = b[n:]
+		field, wireType := key>>3, key&7
+
+		switch wireType {
+		case 0: // varint
+			x, n := binary.Uvarint(b)
+			if n <= 0 {
+				return ErrInvalidProto
+			}
+			b = b[n:]
+			if field == 1 {
+				value = Snowflake(int64(x))
+			}
+		case 1: // 64-bit
+			if len(b) < 8 {
+				return ErrInvalidProto
+			}
+			b = b[8:]
+		case 2: // length-delimited
+			l, n := binary.Uvarint(b)
+			if n <= 0 || l > uint64(len(b)-n) {
+				return ErrInvalidProto
+			}
+			b = b[n+int(l):]
+		case 5: // 32-bit
+			if len(b) < 4 {
+				return ErrInvalidProto
+			}
+			b = b[4:]
+		default:
+			return ErrInvalidProto
+		}
+		if field == 0 || (field == 1 && wireType != 0) {
+			return ErrInvalidProto
+		}
+	}
+	if !value.Valid() {
+		return ErrInvalidSnowflake
+	}
+	v.Value = value
+	return nil
+}
+
+// MarshalJSON follows protojson, which renders the message as
+// {"value":"<decimal>"} because int64 fields are written as strings, and
+// omits the field, giving {}, when it is zero.
+func (v SnowflakeProtoValue) MarshalJSON() ([]byte, error) {
+	if v.Value == 0 {
+		return []byte(`{}`), nil
+	}
+	return []byte(`{"value":"` + strconv.FormatInt(int64(v.Value), 10) + `"}`), nil
+}
@@ -0,0 +1,38 @@
+package snowflake
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSnowflakeProtoValue(t *testing.T) {
+	b, _ := SnowflakeProtoValue{Value: 150}.MarshalProto()
+	if !bytes.Equal(b, []byte{0x08, 0x96, 0x01}) {
+		t.Errorf("(1) 150 encoded as %x, expected 089601!", b)
+	}
+
+	sf := NewSnowflakeNode(2).Next()
+	b, _ = SnowflakeProtoValue{Value: sf}.MarshalProto()
+	var v SnowflakeProtoValue
+	if err := v.UnmarshalProto(b); err != nil || v.Value != sf {
+		t.Errorf("(2) Round trip of %d returned %d, %v!", sf, v.Value, err)
+	}
+
+	// An unknown string field 2 before the value must be skipped.
+	withUnknown := append([]byte{0x12, 0x02, 'h', 'i'}, b...)
+	if err := v.UnmarshalProto(withUnknown); err != nil || v.Value != sf {
+		t.Errorf("(3) Unknown field was not skipped: %d, %v!", v.Value, err)
+	}
+
+	if err := v.UnmarshalProto([]byte{0x08}); err != ErrInvalidProto {
+		t.Errorf("(4) Truncated varint returned %v!", err)
+	}
+
+	j, _ := SnowflakeProtoValue{Value: 150}.MarshalJSON()
+	if string(j) != `{"value":"150"}` {
+		t.Errorf("(5) JSON form is %s!", j)
+	}
+	if j, _ := (SnowflakeProtoValue{}).MarshalJSON(); string(j) != `{}` {
+		t.Errorf("(6) JSON form of zero is %s, expected {}!", j)
+	}
+}
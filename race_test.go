@@ -0,0 +1,95 @@
+//go:build race
+
+package snowflake
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// These tests exercise concurrent use of a node for the race detector
+// and only build with -race.
+
+func TestRaceConcurrentNext(t *testing.T) {
+	node := NewSnowflakeNode(1)
+	deadline := time.Now().Add(time.Second)
+
+	var mu sync.Mutex
+	seen := make(map[Snowflake]bool)
+	var wg sync.WaitGroup
+	for g := 0; g < 50; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var ids []Snowflake
+			for time.Now().Before(deadline) {
+				ids = append(ids, node.Next())
+			}
+			mu.Lock()
+			for _, sf := range ids {
+				if seen[sf] {
+					t.Errorf("(1) Duplicate ID %d!", sf)
+				}
+				seen[sf] = true
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+}
+
+func TestRaceNextWithReaders(t *testing.T) {
+	node := NewSnowflakeNode(1)
+	done := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				info := node.Info()
+				node.TimeResolution()
+				node.Snapshot()
+				node.CurrentSequence()
+				node.CurrentTime()
+				node.Drift()
+				node.Owns(Snowflake(info.NodeID))
+			}
+		}()
+	}
+	for i := 0; i < 10000; i++ {
+		node.Next()
+	}
+	close(done)
+	wg.Wait()
+}
+
+func TestRaceCloseDuringNext(t *testing.T) {
+	a := &countingAssigner{id: 3, bits: 10}
+	node, _ := NewSnowflakeNodeAssigned(a)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 5000; i++ {
+				node.Next()
+			}
+		}()
+	}
+	time.Sleep(time.Millisecond)
+	node.Close()
+	wg.Wait()
+
+	if a.released != 1 {
+		t.Errorf("(1) Assignment released %d times, expected once!", a.released)
+	}
+}
@@ -0,0 +1,110 @@
+package snowflake
+
+import "errors"
+
+// Width of the class (type) field at the bottom of a semantic snowflake.
+const semanticTypeBits = 10
+
+// ErrTypeIDOutOfRange is returned when a type ID does not fit the class
+// field of a semantic snowflake.
+var ErrTypeIDOutOfRange = errors.New("snowflake: type ID out of range")
+
+// ErrObjectIDOutOfRange is returned when the object ID of a semantic
+// snowflake does not fit the positive range of its 41-bit field.
+var ErrObjectIDOutOfRange = errors.New("snowflake: object ID out of range")
+
+// NextSemantic returns a new ID carrying typeID in its class field (the
+// low 10 bits read back as SemanticSnowflake.TypeID). The sequence bits
+// above the class field hold a counter kept per type and millisecond, so
+// IDs of the same type in the same millisecond stay unique; with the
+// default 12 sequence bits that allows 4 IDs per type per millisecond
+// before waiting for the clock. Semantic and plain IDs share the
+// sequence field, so a node should be used for one or the other.
+func (self *SnowflakeNode) NextSemantic(typeID int64) (Snowflake, error) {
+	if typeID < 0 || typeID >= 1<<semanticTypeBits {
+		return 0, ErrTypeIDOutOfRange
+	}
+	if self.seqIdBits <= semanticTypeBits {
+		return 0, ErrInvalidLayout
+	}
+	maxCount := int64(1)<<(self.seqIdBits-semanticTypeBits) - 1
+
+	self.mutex.Lock()
+	now := self.millis()
+	var err error
+	if now < self.semTime {
+		now, err = self.waitPast(self.semTime-1, ErrClockBackwards)
+	}
+	if err == nil && now == self.semTime && self.semCounts[typeID] > maxCount {
+		now, err = self.waitExhausted(self.semTime)
+	}
+	if err != nil {
+		waits := self.takeWaits()
+		self.mutex.Unlock()
+		self.reportWaits(waits)
+		return 0, err
+	}
+	if now != self.semTime || self.semCounts == nil {
+		self.semTime = now
+		self.semCounts = make(map[int64]int64)
+	}
+	count := self.semCounts[typeID]
+	self.semCounts[typeID] = count + 1
+	waits := self.takeWaits()
+	self.mutex.Unlock()
+	self.reportWaits(waits)
+
+	seq := count<<semanticTypeBits | typeID
+	return Snowflake(now<<self.timeStep | self.nodeId<<self.nodeStep | seq), nil
+}
+
+// ToMap returns the fields of s under snake_case keys for structured
+// logging. Values are int64s rather than strings, since logs do not
+// suffer Javascript's precision loss.
+func (s SemanticSnowflake) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"id":             s.ID,
+		"node_id":        s.NodeID,
+		"type_id":        s.TypeID,
+		"global_type_id": s.GlobalTypeID,
+	}
+}
+
+// A FieldChange records a field of a SemanticSnowflake that differs
+// between two snapshots, named as in ToMap.
+type FieldChange struct {
+	Field    string
+	Old, New int64
+}
+
+// Diff returns the fields that differ from s to other, in the order ID,
+// NodeID, TypeID, GlobalTypeID, for change detection in event sourcing.
+// It returns nil if nothing changed.
+func (s SemanticSnowflake) Diff(other SemanticSnowflake) []FieldChange {
+	var changes []FieldChange
+	for _, f := range []FieldChange{
+		{"id", s.ID, other.ID},
+		{"node_id", s.NodeID, other.NodeID},
+		{"type_id", s.TypeID, other.TypeID},
+		{"global_type_id", s.GlobalTypeID, other.GlobalTypeID},
+	} {
+		if f.Old != f.New {
+			changes = append(changes, f)
+		}
+	}
+	return changes
+}
+
+// ToSnowflakeChecked is ToSnowflake with bounds checking: it returns
+// ErrObjectIDOutOfRange rather than a corrupt ID when s.ID is negative,
+// does not fit the 41-bit object field, or would set the sign bit.
+func (s SemanticSnowflake) ToSnowflakeChecked() (Snowflake, error) {
+	if s.ID < 0 || s.ID >= 1<<41 {
+		return 0, ErrObjectIDOutOfRange
+	}
+	sf := s.ToSnowflake()
+	if sf < 0 {
+		return 0, ErrObjectIDOutOfRange
+	}
+	return sf, nil
+}
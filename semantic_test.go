@@ -0,0 +1,65 @@
+package snowflake
+
+import "testing"
+
+func TestNextSemantic(t *testing.T) {
+	node := NewSnowflakeNode(6)
+	seen := make(map[Snowflake]bool)
+	for i := 0; i < 50; i++ {
+		for _, typeID := range []int64{1, 100, 1023} {
+			sf, err := node.NextSemantic(typeID)
+			if err != nil {
+				t.Fatalf("(1) NextSemantic(%d) failed: %v", typeID, err)
+			}
+			if got := NewSemanticSnowflake(sf).TypeID; got != typeID {
+				t.Errorf("(2) ID %d has type %d, expected %d!", sf, got, typeID)
+			}
+			if seen[sf] {
+				t.Errorf("(3) Duplicate semantic ID %d!", sf)
+			}
+			seen[sf] = true
+		}
+	}
+
+	if _, err := node.NextSemantic(1024); err != ErrTypeIDOutOfRange {
+		t.Errorf("(4) Type 1024 was not rejected, got %v!", err)
+	}
+}
+
+func TestSemanticSnowflakeToSnowflakeChecked(t *testing.T) {
+	sf := Snowflake(2856524282194824821)
+	if got, err := sf.Semantic().ToSnowflakeChecked(); err != nil || got != sf {
+		t.Errorf("(1) ToSnowflakeChecked returned %d, %v for %d!", got, err, sf)
+	}
+	for _, id := range []int64{-1, 1 << 40, 1<<41 - 1, 1 << 41, 1 << 50} {
+		s := SemanticSnowflake{ID: id, NodeID: 5, TypeID: 7}
+		if _, err := s.ToSnowflakeChecked(); err != ErrObjectIDOutOfRange {
+			t.Errorf("(2) Object ID %d was not rejected, got %v!", id, err)
+		}
+	}
+}
+
+func TestSemanticSnowflakeDiff(t *testing.T) {
+	s := SemanticSnowflake{ID: 1, NodeID: 2, TypeID: 3, GlobalTypeID: 4}
+	if changes := s.Diff(s); changes != nil {
+		t.Errorf("(1) Unchanged snapshot gave %v!", changes)
+	}
+
+	moved := s
+	moved.NodeID = 20
+	changes := s.Diff(moved)
+	if len(changes) != 1 || changes[0] != (FieldChange{Field: "node_id", Old: 2, New: 20}) {
+		t.Errorf("(2) Node change gave %v!", changes)
+	}
+
+	changes = s.Diff(SemanticSnowflake{ID: 10, NodeID: 20, TypeID: 30, GlobalTypeID: 40})
+	fields := s.ToMap()
+	if len(changes) != 4 {
+		t.Fatalf("(3) Expected 4 changes, got %v!", changes)
+	}
+	for _, c := range changes {
+		if old, ok := fields[c.Field]; !ok || old != c.Old || c.New != c.Old*10 {
+			t.Errorf("(4) Unexpected change %+v!", c)
+		}
+	}
+}
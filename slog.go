@@ -0,0 +1,27 @@
+//go:build go1.21
+
+package snowflake
+
+import "log/slog"
+
+// LogValue implements slog.LogValuer, logging s as a group with the same
+// keys as ToMap.
+func (s SemanticSnowflake) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int64("id", s.ID),
+		slog.Int64("node_id", s.NodeID),
+		slog.Int64("type_id", s.TypeID),
+		slog.Int64("global_type_id", s.GlobalTypeID),
+	)
+}
+
+// LogValue implements slog.LogValuer, logging sf as a group of its
+// decimal form (so logs stay greppable by exact ID), generation time in
+// UTC (so logs do not depend on the host's time zone) and node.
+func (sf Snowflake) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("id", sf.String()),
+		slog.Time("time", sf.Time().UTC()),
+		slog.Int64("node", sf.Node()),
+	)
+}
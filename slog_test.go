@@ -0,0 +1,42 @@
+//go:build go1.21
+
+package snowflake
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSemanticSnowflakeLogValue(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	s := SemanticSnowflake{ID: 42, NodeID: 50, TypeID: 100, GlobalTypeID: 51300}
+	logger.Info("created", "flake", s)
+
+	out := buf.String()
+	for _, want := range []string{"flake.id=42", "flake.node_id=50", "flake.type_id=100", "flake.global_type_id=51300"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("(1) Log line %q is missing %q!", out, want)
+		}
+	}
+
+	if m := s.ToMap(); m["node_id"] != int64(50) || len(m) != 4 {
+		t.Errorf("(2) ToMap returned %v!", m)
+	}
+}
+
+func TestSnowflakeLogValue(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	sf := Snowflake(1000<<22 | 5<<12 | 3)
+	logger.Info("created", "flake", sf)
+
+	out := buf.String()
+	for _, want := range []string{`"id":"4194324483"`, `"time":"2021-01-21T18:00:01Z"`, `"node":5`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("(1) Log line %q is missing %q!", out, want)
+		}
+	}
+}
@@ -1,8 +1,16 @@
 package snowflake
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestSnowflakeConversion(t *testing.T) {
@@ -44,3 +52,665 @@ func TestSnowflakeConversion(t *testing.T) {
 	fmt.Printf("OCID: %d\n", s3.GlobalTypeID)
 
 }
+
+func TestSnowflakeNodeOwns(t *testing.T) {
+	n1 := NewSnowflakeNode(5)
+	n2 := NewSnowflakeNode(6)
+
+	// Generate enough IDs to use the high sequence bits, which must not
+	// bleed into the node field.
+	for i := 0; i < 5000; i++ {
+		sf := n1.Next()
+		if !n1.Owns(sf) {
+			t.Fatalf("(1) Node 5 does not own its own ID %d!", sf)
+		}
+		if n2.Owns(sf) {
+			t.Fatalf("(2) Node 6 claims ID %d generated by node 5!", sf)
+		}
+	}
+}
+
+func TestSemanticSnowflakeEqual(t *testing.T) {
+	s1 := SemanticSnowflake{ID: 42, NodeID: 50, TypeID: 100, GlobalTypeID: 51300}
+	s2 := SemanticSnowflake{ID: 42, NodeID: 50, TypeID: 100, GlobalTypeID: 0}
+
+	if !s1.Equal(s2) {
+		t.Errorf("(1) %+v and %+v differ only in GlobalTypeID but are not equal!", s1, s2)
+	}
+
+	s2.TypeID = 101
+	if s1.Equal(s2) {
+		t.Errorf("(2) %+v and %+v have different type IDs but are equal!", s1, s2)
+	}
+}
+
+func TestSemanticSnowflakeIsSystemReserved(t *testing.T) {
+	if s := NewSemanticSnowflake(Snowflake(42<<23 | 0<<10 | 7)); !s.IsSystemReserved() {
+		t.Errorf("(1) %+v from system 0 is not reported as reserved!", s)
+	}
+	if s := NewSemanticSnowflake(Snowflake(42<<23 | 1<<10 | 7)); s.IsSystemReserved() {
+		t.Errorf("(2) %+v from system 1 is reported as reserved!", s)
+	}
+}
+
+func TestSecureNodeNoDuplicates(t *testing.T) {
+	node := NewSecureNode(7)
+	const goroutines, perGoroutine = 8, 1000
+
+	var mu sync.Mutex
+	seen := make(map[Snowflake]bool, goroutines*perGoroutine)
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perGoroutine; i++ {
+				sf := node.Next()
+				mu.Lock()
+				if seen[sf] {
+					t.Errorf("(1) Duplicate secure ID %d!", sf)
+				}
+				seen[sf] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestSecureNodeRandomFailure(t *testing.T) {
+	node := NewSecureNode(7)
+	defer func(r io.Reader) { randomReader = r }(randomReader)
+	randomReader = failingReader{}
+
+	if _, err := node.NextE(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("(1) Expected the random source error, got %v!", err)
+	}
+	if sf := node.Next(); sf != -1 {
+		t.Errorf("(2) Expected -1 from Next, got %d!", sf)
+	}
+}
+
+// nanoWaitStrategy moves the frozen clock to offset into the next
+// millisecond when consulted.
+type nanoWaitStrategy struct {
+	now    *time.Time
+	offset time.Duration
+}
+
+func (s nanoWaitStrategy) OnExhausted(*SnowflakeNode, int64) error {
+	*s.now = s.now.Truncate(time.Millisecond).Add(time.Millisecond + s.offset)
+	return nil
+}
+
+func TestNanoNode(t *testing.T) {
+	now := time.UnixMilli(baseEpoch + 1000).Add(500 * time.Microsecond)
+	node := NewNanoNode(7)
+	node.clock = func() time.Time { return now }
+
+	sf := node.Next()
+	if sf.RawMillis() != 1000 || sf.Node() != 7 || sf.Sequence() != 2048 {
+		t.Errorf("(1) Expected sequence 2048 half way through the millisecond, got %d!", sf.Sequence())
+	}
+	if next := node.Next(); next.Sequence() != 2049 {
+		t.Errorf("(2) Expected a collision to take slot 2049, got %d!", next.Sequence())
+	}
+	now = now.Add(100 * time.Microsecond)
+	if next := node.Next(); next.Sequence() != 2457 {
+		t.Errorf("(3) Expected slot 2457 at 600us, got %d!", next.Sequence())
+	}
+
+	now = time.UnixMilli(baseEpoch + 1001).Add(time.Millisecond - 1)
+	if next := node.Next(); next.RawMillis() != 1001 || next.Sequence() != 4095 {
+		t.Errorf("(4) Expected the last slot of the millisecond, got %d!", next)
+	}
+	if _, err := node.NextNonBlocking(); err != ErrExhausted {
+		t.Errorf("(5) Expected ErrExhausted past the last slot, got %v!", err)
+	}
+	now = now.Add(1)
+	if next := node.Next(); next.RawMillis() != 1002 || next.Sequence() != 0 {
+		t.Errorf("(6) Expected slot 0 of the next millisecond, got %d!", next)
+	}
+
+	// An ID issued after waiting takes the slot the wait ended in
+	now = time.UnixMilli(baseEpoch + 1003).Add(time.Millisecond - 1)
+	node.Next()
+	node.strategy = nanoWaitStrategy{now: &now, offset: 300 * time.Microsecond}
+	if next := node.Next(); next.RawMillis() != 1004 || next.Sequence() != 1228 {
+		t.Errorf("(7) Expected slot 1228 at 300us after waiting, got %d at %d!", next.Sequence(), next.RawMillis())
+	}
+
+	real := NewNanoNode(3)
+	last := real.Next()
+	for i := 0; i < 20000; i++ {
+		if sf := real.Next(); sf <= last {
+			t.Fatalf("(8) ID %d after %d is not increasing!", sf, last)
+		} else {
+			last = sf
+		}
+	}
+}
+
+func TestToUint32s(t *testing.T) {
+	node := NewSnowflakeNode(5)
+	for _, sf := range []Snowflake{0, 1, 1<<32 - 1, 1 << 32, MaxSnowflake, node.Next()} {
+		high, low := sf.ToUint32s()
+		if back := SnowflakeFromUint32s(high, low); back != sf {
+			t.Errorf("(1) %d split into %#x, %#x and joined back to %d!", sf, high, low, back)
+		}
+	}
+	if high, low := Snowflake(2856524282194824821).ToUint32s(); high != 0x27a469b3 || low != 0x3c800e75 {
+		t.Errorf("(2) Split into %#x, %#x!", high, low)
+	}
+
+	// The top bit of high is the sign bit of the result
+	sf := SnowflakeFromUint32s(0x80000000, 1)
+	if sf.Valid() || sf != Snowflake(math.MinInt64+1) {
+		t.Errorf("(3) Joining a high half with its top bit set gave %d!", sf)
+	}
+	if high, low := sf.ToUint32s(); high != 0x80000000 || low != 1 {
+		t.Errorf("(4) Negative ID split into %#x, %#x!", high, low)
+	}
+}
+
+func TestSnowflakeFromUint64(t *testing.T) {
+	sf, err := SnowflakeFromUint64(2856524282194824821)
+	if err != nil || sf.AsUint64() != 2856524282194824821 {
+		t.Errorf("(1) Round trip of 2856524282194824821 returned %d, %v!", sf, err)
+	}
+
+	if _, err := SnowflakeFromUint64(1 << 63); err != ErrInvalidSnowflake {
+		t.Errorf("(2) High bit set was not rejected, got %v!", err)
+	}
+}
+
+func FuzzParseSnowflake(f *testing.F) {
+	for _, seed := range []string{"0", "9223372036854775807", "-1", "18446744073709551615", "", "abc"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		sf, err := ParseSnowflake(s)
+		if err == nil && !sf.Valid() {
+			t.Errorf("ParseSnowflake(%q) returned invalid ID %d!", s, sf)
+		}
+	})
+}
+
+func FuzzUnmarshalJSON(f *testing.F) {
+	for _, seed := range []string{`"0"`, `"9223372036854775807"`, `-1`, `1e300`, `""`, `"abc"`, `null`, `true`} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var sf Snowflake
+		if err := sf.UnmarshalJSON(b); err == nil && !sf.Valid() {
+			t.Errorf("UnmarshalJSON(%q) returned invalid ID %d!", b, sf)
+		}
+	})
+}
+
+func FuzzUnmarshalBinary(f *testing.F) {
+	f.Add([]byte{0, 0, 0, 0, 0, 0, 0, 0})
+	f.Add([]byte{0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var sf Snowflake
+		if err := sf.UnmarshalBinary(b); err == nil && !sf.Valid() {
+			t.Errorf("UnmarshalBinary(%x) returned invalid ID %d!", b, sf)
+		}
+	})
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, io.ErrUnexpectedEOF
+}
+
+func TestNewRandomNode(t *testing.T) {
+	node, err := NewRandomNode()
+	if err != nil {
+		t.Fatalf("(1) Could not create node: %v", err)
+	}
+	if node.nodeId < 0 || node.nodeId >= 1<<baseNodeBits {
+		t.Errorf("(2) Node ID %d does not fit the node field!", node.nodeId)
+	}
+
+	defer func(r io.Reader) { randomReader = r }(randomReader)
+	randomReader = failingReader{}
+	if node, err := NewRandomNode(); !errors.Is(err, io.ErrUnexpectedEOF) || node != nil {
+		t.Errorf("(3) Expected random source error, got %v, %v!", node, err)
+	}
+	if node := NewRandomNodeOr(42); node.nodeId != 42 {
+		t.Errorf("(4) Expected fallback node ID 42, got %d!", node.nodeId)
+	}
+}
+
+func TestNodeIDFromKey(t *testing.T) {
+	id := NodeIDFromKey("tenant-a", 8)
+	if id != NodeIDFromKey("tenant-a", 8) {
+		t.Errorf("(1) NodeIDFromKey is not deterministic!")
+	}
+	if id < 0 || id >= 256 {
+		t.Errorf("(2) Node ID %d does not fit 8 bits!", id)
+	}
+
+	node, err := NewSnowflakeNodeWithBits(id, 8, 14)
+	if err != nil {
+		t.Fatalf("(3) Could not create node: %v", err)
+	}
+	if !node.Owns(node.Next()) {
+		t.Errorf("(4) Node %d does not own its own ID!", id)
+	}
+
+	if _, err := NewSnowflakeNodeWithBits(256, 8, 14); err != ErrNodeIDOutOfRange {
+		t.Errorf("(5) Oversized node ID was not rejected, got %v!", err)
+	}
+}
+
+func TestSnowflakeNodeUniqueAndMonotonic(t *testing.T) {
+	goroutines, perGoroutine := 100, 10000
+	if testing.Short() {
+		perGoroutine = 1000
+	}
+	node := NewSnowflakeNode(3)
+
+	var mu sync.Mutex
+	var all []Snowflake
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			ids := make([]Snowflake, perGoroutine)
+			for i := range ids {
+				ids[i] = node.Next()
+			}
+			if !sort.SliceIsSorted(ids, func(a, b int) bool { return ids[a] < ids[b] }) {
+				t.Errorf("(1) IDs from goroutine %d are not in increasing order!", g)
+			}
+			mu.Lock()
+			all = append(all, ids...)
+			mu.Unlock()
+		}(g)
+	}
+	wg.Wait()
+
+	sort.Slice(all, func(a, b int) bool { return all[a] < all[b] })
+	for i := 1; i < len(all); i++ {
+		if all[i] == all[i-1] {
+			t.Fatalf("(2) Duplicate ID %d!", all[i])
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := Snowflake(1000<<22 | 5<<12 | 3)
+	b := Snowflake(1000<<22 | 5<<12 | 5)
+	c := Snowflake(1001<<22 | 2<<12 | 0)
+
+	if d := Diff(a, b); d != "same ms, a.seq=3 < b.seq=5" {
+		t.Errorf("(1) Unexpected diff %q!", d)
+	}
+	if d := Diff(c, a); d != "a.ms=1001 > b.ms=1000" {
+		t.Errorf("(2) Unexpected diff %q!", d)
+	}
+	if d := Diff(a, a); d != "identical" {
+		t.Errorf("(3) Unexpected diff %q!", d)
+	}
+}
+
+func TestSequenceOrdinal(t *testing.T) {
+	now := time.Now()
+	node, _ := New(3, WithClock(func() time.Time { return now }))
+	for i := int64(0); i < 5; i++ {
+		if sf := node.Next(); sf.SequenceOrdinal() != i || sf.Sequence() != i {
+			t.Errorf("(1) ID %d of the millisecond has ordinal %d!", i, sf.SequenceOrdinal())
+		}
+	}
+
+	now = now.Add(time.Millisecond)
+	if sf := node.Next(); sf.SequenceOrdinal() != 0 {
+		t.Errorf("(2) First ID of a new millisecond has ordinal %d!", sf.SequenceOrdinal())
+	}
+}
+
+func TestBinaryLayout(t *testing.T) {
+	sf := Snowflake(1000<<22 | 5<<12 | 3)
+	want := "0|00000000000000000000000000000001111101000|0000000101|000000000011"
+	if got := sf.BinaryLayout(); got != want {
+		t.Errorf("(1) Expected layout %s, got %s!", want, got)
+	}
+	if got := Snowflake(math.MaxInt64).BinaryLayout(); got != "0|"+strings.Repeat("1", 41)+"|"+strings.Repeat("1", 10)+"|"+strings.Repeat("1", 12) {
+		t.Errorf("(2) Unexpected layout %s for the largest ID!", got)
+	}
+}
+
+func TestNextNonBlocking(t *testing.T) {
+	node := NewSnowflakeNode(1)
+
+	// Far more than one millisecond's worth of sequence numbers, so at
+	// least one call must run out unless the clock is very slow.
+	exhausted := false
+	for i := 0; i < 100000 && !exhausted; i++ {
+		_, err := node.NextNonBlocking()
+		if err == ErrExhausted {
+			exhausted = true
+		} else if err != nil {
+			t.Fatalf("(1) Unexpected error %v!", err)
+		}
+	}
+	if !exhausted {
+		t.Skip("clock advanced before the sequence was exhausted")
+	}
+
+	if sf := node.Next(); !node.Owns(sf) {
+		t.Errorf("(2) Next after exhaustion returned foreign ID %d!", sf)
+	}
+}
+
+func TestSnowflakeTime(t *testing.T) {
+	sf := Snowflake(1000<<22 | 5<<12 | 3)
+	if sf.RawMillis() != 1000 || sf.TimestampMs() != 1000 {
+		t.Errorf("(1) RawMillis and TimestampMs returned %d and %d, expected 1000!", sf.RawMillis(), sf.TimestampMs())
+	}
+	if sf.UnixMillis() != baseEpoch+1000 {
+		t.Errorf("(2) UnixMillis returned %d, expected %d!", sf.UnixMillis(), baseEpoch+1000)
+	}
+
+	now := NewSnowflakeNode(1).Next()
+	if age := now.Age(); age < 0 || age > time.Second {
+		t.Errorf("(3) Fresh ID has age %v!", age)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	ts := time.UnixMilli(baseEpoch + 1000)
+	sf, err := Encode(ts, 5, 3)
+	if err != nil || sf != Snowflake(1000<<22|5<<12|3) {
+		t.Errorf("(1) Encode returned %d, %v!", sf, err)
+	}
+	if !sf.Time().Equal(ts) {
+		t.Errorf("(2) Encoded time %v, expected %v!", sf.Time(), ts)
+	}
+
+	if _, err := Encode(time.UnixMilli(baseEpoch-1), 5, 3); err != ErrTimestampOverflow {
+		t.Errorf("(3) Time before the epoch was not rejected, got %v!", err)
+	}
+	if _, err := Encode(ts, 1024, 3); err != ErrNodeIDOutOfRange {
+		t.Errorf("(4) Node 1024 was not rejected, got %v!", err)
+	}
+}
+
+func TestParseLoose(t *testing.T) {
+	for _, s := range []string{"12345", " 12345\n", `"12345"`, "'12345'", "\"12345\"\r\n"} {
+		if sf, err := ParseLoose(s); err != nil || sf != 12345 {
+			t.Errorf("(1) ParseLoose(%q) returned %d, %v!", s, sf, err)
+		}
+	}
+
+	for _, s := range []string{"", `""`, `"12345`, `""12345""`, "12 345", "-1"} {
+		if _, err := ParseLoose(s); err == nil {
+			t.Errorf("(2) ParseLoose(%q) did not fail!", s)
+		}
+	}
+}
+
+func TestDisjointNodes(t *testing.T) {
+	n1, n2 := NewSnowflakeNode(1), NewSnowflakeNode(2)
+	a := []Snowflake{n1.Next(), n1.Next()}
+	b := []Snowflake{n2.Next(), n2.Next()}
+
+	if !DisjointNodes(a, b) {
+		t.Errorf("(1) IDs from nodes 1 and 2 overlap!")
+	}
+	if DisjointNodes(a, append(b, n1.Next())) {
+		t.Errorf("(2) Streams sharing node 1 are disjoint!")
+	}
+}
+
+func TestSequenceGaps(t *testing.T) {
+	ms := int64(1000) << baseTimeShift
+	ids := []Snowflake{
+		Snowflake(ms | 1<<12 | 5),
+		Snowflake(ms | 1<<12 | 2),
+		Snowflake(ms | 2<<12 | 9),
+		Snowflake(ms | 1<<12 | 3),
+		Snowflake(ms | 2<<12 | 7),
+		Snowflake(ms | 1<<12 | 5),
+		// The next millisecond on node 1 starts its own group
+		Snowflake(ms + 1<<baseTimeShift | 1<<12 | 0),
+	}
+	gaps := SequenceGaps(ids)
+	expected := []Snowflake{Snowflake(ms | 1<<12 | 4), Snowflake(ms | 2<<12 | 8)}
+	if len(gaps) != len(expected) {
+		t.Fatalf("(1) Expected gaps %v, got %v!", expected, gaps)
+	}
+	for i := range gaps {
+		if gaps[i] != expected[i] {
+			t.Errorf("(2) Expected gap %d, got %d!", expected[i], gaps[i])
+		}
+	}
+	if ids[0] != Snowflake(ms|1<<12|5) {
+		t.Errorf("(3) SequenceGaps modified its input!")
+	}
+	if gaps := SequenceGaps(NewSnowflakeNode(1).NextN(100)); len(gaps) != 0 {
+		t.Errorf("(4) A node's own IDs have gaps %v!", gaps)
+	}
+}
+
+func TestNetSnowflakeParseID(t *testing.T) {
+	for _, s := range []NetSnowflake{"0", "42", "2856524282194824821", "9223372036854775807"} {
+		id, ok := s.ParseID()
+		if !ok || id != s.ToID() || s.ToIDUnsafe() != id {
+			t.Errorf("(1) %q parsed as %d, %v!", s, id, ok)
+		}
+	}
+	for _, s := range []NetSnowflake{"", "-1", "+1", "4x2", " 42", "9223372036854775808", "18446744073709551616"} {
+		if id, ok := s.ParseID(); ok {
+			t.Errorf("(2) Invalid %q parsed as %d!", s, id)
+		}
+	}
+}
+
+func BenchmarkNetSnowflakeToID(b *testing.B) {
+	ids := []NetSnowflake{"2856524282194824821", "not-an-id"}
+	for i := 0; i < b.N; i++ {
+		s := ids[i%2]
+		if s.Valid() {
+			s.ToID()
+		}
+	}
+}
+
+func BenchmarkNetSnowflakeParseID(b *testing.B) {
+	ids := []NetSnowflake{"2856524282194824821", "not-an-id"}
+	for i := 0; i < b.N; i++ {
+		ids[i%2].ParseID()
+	}
+}
+
+func BenchmarkNetSnowflakeToIDUnsafe(b *testing.B) {
+	s := NetSnowflake("2856524282194824821")
+	for i := 0; i < b.N; i++ {
+		s.ToIDUnsafe()
+	}
+}
+
+func TestNetSnowflakeCompare(t *testing.T) {
+	if c, err := NetSnowflake("10").Compare("9"); c != 1 || err != nil {
+		t.Errorf("(1) \"10\" compared to \"9\" gave %d, %v!", c, err)
+	}
+	if c, err := NetSnowflake("9").Compare("10"); c != -1 || err != nil {
+		t.Errorf("(2) \"9\" compared to \"10\" gave %d, %v!", c, err)
+	}
+	if c, err := NetSnowflake("42").Compare("42"); c != 0 || err != nil {
+		t.Errorf("(3) \"42\" compared to itself gave %d, %v!", c, err)
+	}
+	if _, err := NetSnowflake("42").Compare("4x2"); !errors.Is(err, ErrInvalidNetSnowflake) {
+		t.Errorf("(4) Invalid ID gave error %v!", err)
+	}
+}
+
+func TestFingerprintDistribution(t *testing.T) {
+	const buckets, perBucket = 256, 400
+	var counts [buckets]int
+	node := NewSnowflakeNode(1)
+	for i := 0; i < buckets*perBucket; i++ {
+		counts[node.Next().Fingerprint()%buckets]++
+	}
+
+	// Chi-squared with 255 degrees of freedom; 330 is well past p=0.001.
+	chi := 0.0
+	for _, c := range counts {
+		d := float64(c - perBucket)
+		chi += d * d / perBucket
+	}
+	if chi > 330 {
+		t.Errorf("(1) Low bits of fingerprints are skewed, chi-squared %.1f!", chi)
+	}
+}
+
+func TestValidFromNodes(t *testing.T) {
+	sf := NewSnowflakeNode(7).Next()
+	if !ValidFromNodes(sf, []int64{3, 7}) {
+		t.Errorf("(1) ID from node 7 was rejected!")
+	}
+	if ValidFromNodes(sf, []int64{3, 8}) {
+		t.Errorf("(2) ID from node 7 was accepted!")
+	}
+
+	if !sf.PlausibleAt(time.Now(), time.Second) {
+		t.Errorf("(3) Fresh ID is implausible!")
+	}
+	future, _ := Encode(time.Now().Add(time.Hour), 7, 0)
+	if future.PlausibleAt(time.Now(), time.Second) {
+		t.Errorf("(4) ID from an hour ahead is plausible!")
+	}
+}
+
+func TestSnowflakeFlag(t *testing.T) {
+	var sf Snowflake
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Var(&sf, "id", "ID to look up")
+
+	if err := fs.Parse([]string{"-id", "12345"}); err != nil || sf != 12345 {
+		t.Errorf("(1) -id 12345 parsed as %d, %v!", sf, err)
+	}
+	if sf.String() != "12345" {
+		t.Errorf("(2) String returned %q!", sf.String())
+	}
+	if err := fs.Parse([]string{"-id", "bogus"}); err == nil {
+		t.Errorf("(3) -id bogus was accepted!")
+	}
+}
+
+func TestAtTime(t *testing.T) {
+	node := NewSnowflakeNode(1)
+	for attempt := 0; attempt < 10; attempt++ {
+		before := time.Now()
+		sf := node.Next()
+		now := time.Now()
+		if before.UnixMilli() != now.UnixMilli() {
+			continue // crossed a millisecond boundary, try again
+		}
+		if !sf.AtTime(now, baseEpoch) {
+			t.Errorf("(1) Fresh ID %d is not at %v!", sf, now)
+		}
+		if sf.AtTime(now.Add(time.Millisecond), baseEpoch) {
+			t.Errorf("(2) Fresh ID %d is at %v!", sf, now.Add(time.Millisecond))
+		}
+		return
+	}
+	t.Skip("could not generate an ID within a single millisecond")
+}
+
+func TestSnowflakeFormat(t *testing.T) {
+	sf, _ := Encode(time.Date(2023, 5, 17, 13, 45, 0, 0, time.UTC), 1, 0)
+	if s := sf.Format(time.RFC3339, nil); s != "2023-05-17T13:45:00Z" {
+		t.Errorf("(1) Format with nil location returned %q!", s)
+	}
+
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	if s := sf.Format(time.RFC3339, loc); s != "2023-05-17T15:45:00+02:00" {
+		t.Errorf("(2) Format in UTC+2 returned %q!", s)
+	}
+}
+
+func TestRebase(t *testing.T) {
+	ours := time.UnixMilli(baseEpoch)
+	theirs := ours.Add(-24 * time.Hour)
+	sf := Snowflake(1000<<22 | 5<<12 | 3)
+
+	moved, err := sf.Rebase(ours, theirs)
+	if err != nil || moved.RawMillis() != 1000+24*60*60*1000 || moved&0x3fffff != sf&0x3fffff {
+		t.Errorf("(1) Rebase returned %d, %v!", moved, err)
+	}
+	if back, _ := moved.Rebase(theirs, ours); back != sf {
+		t.Errorf("(2) Rebasing back returned %d, expected %d!", back, sf)
+	}
+
+	if _, err := sf.Rebase(theirs, ours); err != ErrTimestampOverflow {
+		t.Errorf("(3) Underflow returned %v!", err)
+	}
+}
+
+func TestSnowflakeSemantic(t *testing.T) {
+	sf := Snowflake(2856524282194824821)
+	if s := sf.Semantic(); s != NewSemanticSnowflake(sf) || s.ToSnowflake() != sf {
+		t.Errorf("(1) Semantic returned %+v for %d!", s, sf)
+	}
+}
+
+func TestSemanticSnowflakeToSnowflakeInt64(t *testing.T) {
+	s := NewSemanticSnowflake(2856524282194824821)
+	if i := s.ToSnowflakeInt64(); i != 2856524282194824821 {
+		t.Errorf("(1) ToSnowflakeInt64 returned %d!", i)
+	}
+	if str := (&s).ToSnowflakeString(); str != "2856524282194824821" {
+		t.Errorf("(2) ToSnowflakeString returned %q!", str)
+	}
+}
+
+func TestSemanticSnowflakeClone(t *testing.T) {
+	p := &SemanticSnowflake{ID: 42, NodeID: 50, TypeID: 100}
+	c := p.Clone()
+	p.ID = 43
+	if c.ID != 42 {
+		t.Errorf("(1) Clone shares state with the original!")
+	}
+}
+
+func TestWithNode(t *testing.T) {
+	sf := Snowflake(1000<<22 | 5<<12 | 4095)
+	for _, node := range []int64{0, 6, 1023} {
+		moved, err := sf.WithNode(node)
+		if err != nil || moved.Node() != node || moved.RawMillis() != 1000 || moved.Sequence() != 4095 {
+			t.Errorf("(1) Moving %d to node %d gave %d, %v!", sf, node, moved, err)
+		}
+	}
+	if _, err := sf.WithNode(1024); err != ErrNodeIDOutOfRange {
+		t.Errorf("(2) Node 1024 was not rejected, got %v!", err)
+	}
+	if _, err := sf.WithNode(-1); err != ErrNodeIDOutOfRange {
+		t.Errorf("(3) Node -1 was not rejected, got %v!", err)
+	}
+	if _, err := Snowflake(-1).WithNode(1); err != ErrInvalidSnowflake {
+		t.Errorf("(4) Invalid ID was not rejected, got %v!", err)
+	}
+}
+
+func TestSameMillis(t *testing.T) {
+	now := time.Now()
+	node, _ := New(1, WithClock(func() time.Time { return now }))
+	a, b := node.Next(), node.Next()
+	if !a.SameMillis(b) {
+		t.Errorf("(1) IDs %d and %d from one burst are not in the same millisecond!", a, b)
+	}
+
+	now = now.Add(time.Millisecond)
+	if c := node.Next(); a.SameMillis(c) {
+		t.Errorf("(2) IDs %d and %d a millisecond apart are in the same millisecond!", a, c)
+	}
+}
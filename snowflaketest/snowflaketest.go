@@ -0,0 +1,67 @@
+// Package snowflaketest provides helpers for testing code that uses
+// snowflake IDs.
+package snowflaketest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/cmertens/snowflake"
+)
+
+// GenerateSeededSnowflakes returns count IDs in the default layout that
+// are fully determined by its arguments: the timestamp field is fixed at
+// seed milliseconds since the epoch and the sequence runs from 0 to
+// count-1. epochMs is the Unix time in milliseconds of the epoch the
+// fixtures stand in for; it does not change the bits, since IDs only
+// record the offset from their epoch. It panics if nodeId, seed or count
+// do not fit the layout.
+func GenerateSeededSnowflakes(nodeId int, epochMs int64, seed int64, count int) []snowflake.Snowflake {
+	ids := make([]snowflake.Snowflake, count)
+	for i := range ids {
+		sf, err := snowflake.DefaultLayout.Pack(seed, int64(nodeId), int64(i))
+		if err != nil {
+			panic(fmt.Sprintf("snowflaketest: seeded ID %d (node %d, seed %d, epoch %d): %v", i, nodeId, seed, epochMs, err))
+		}
+		ids[i] = sf
+	}
+	return ids
+}
+
+// AssertNoDuplicates calls Next on node from goroutines goroutines at
+// once, perGoroutine times each, and fails t if any ID is issued twice or
+// cannot be generated. Use it to check a node configuration under
+// realistic concurrency.
+func AssertNoDuplicates(t testing.TB, node *snowflake.SnowflakeNode, goroutines, perGoroutine int) {
+	t.Helper()
+	results := make([][]snowflake.Snowflake, goroutines)
+	var wg sync.WaitGroup
+	for g := range results {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			ids := make([]snowflake.Snowflake, 0, perGoroutine)
+			for i := 0; i < perGoroutine; i++ {
+				sf, err := node.NextE()
+				if err != nil {
+					t.Errorf("snowflaketest: goroutine %d, ID %d: %v", g, i, err)
+					break
+				}
+				ids = append(ids, sf)
+			}
+			results[g] = ids
+		}(g)
+	}
+	wg.Wait()
+
+	seen := make(map[snowflake.Snowflake]int, goroutines*perGoroutine)
+	for g, ids := range results {
+		for _, sf := range ids {
+			if other, ok := seen[sf]; ok {
+				t.Errorf("snowflaketest: ID %d issued to goroutines %d and %d", sf, other, g)
+			}
+			seen[sf] = g
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package snowflaketest
+
+import (
+	"testing"
+
+	"github.com/cmertens/snowflake"
+)
+
+func TestGenerateSeededSnowflakes(t *testing.T) {
+	a := GenerateSeededSnowflakes(5, 1611252000000, 1000, 10)
+	b := GenerateSeededSnowflakes(5, 1611252000000, 1000, 10)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Errorf("(1) Seeded ID %d differs between runs: %d != %d!", i, a[i], b[i])
+		}
+		if a[i].RawMillis() != 1000 || a[i].Node() != 5 || int(a[i])&0xfff != i {
+			t.Errorf("(2) Seeded ID %d has unexpected fields!", a[i])
+		}
+	}
+}
+
+func TestAssertNoDuplicates(t *testing.T) {
+	AssertNoDuplicates(t, snowflake.NewSnowflakeNode(3), 8, 2000)
+	AssertNoDuplicates(t, snowflake.NewSecureNode(4), 4, 500)
+}
@@ -0,0 +1,59 @@
+package snowflake
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+)
+
+// Scan implements sql.Scanner. Besides native int64 columns it accepts
+// decimal strings and []byte, which is how go-sql-driver/mysql returns
+// BIGINT columns by default.
+func (sf *Snowflake) Scan(src interface{}) error {
+	var i int64
+	switch v := src.(type) {
+	case int64:
+		i = v
+	case []byte:
+		p, err := strconv.ParseInt(string(v), 10, 64)
+		if err != nil {
+			return err
+		}
+		i = p
+	case string:
+		p, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return err
+		}
+		i = p
+	default:
+		return fmt.Errorf("snowflake: cannot scan %T into Snowflake", src)
+	}
+	if i < 0 {
+		return ErrInvalidSnowflake
+	}
+	*sf = Snowflake(i)
+	return nil
+}
+
+// Value implements driver.Valuer, storing the ID as a native int64.
+func (sf Snowflake) Value() (driver.Value, error) {
+	return int64(sf), nil
+}
+
+// StringSnowflake is a Snowflake stored as a decimal string, for
+// databases and drivers that pass values through JSON or Javascript,
+// where an int64 would lose precision. Convert with
+// StringSnowflake(sf) and Snowflake(ss).
+type StringSnowflake Snowflake
+
+// Scan implements sql.Scanner, accepting the same values as
+// Snowflake.Scan.
+func (ss *StringSnowflake) Scan(src interface{}) error {
+	return (*Snowflake)(ss).Scan(src)
+}
+
+// Value implements driver.Valuer, storing the ID as a decimal string.
+func (ss StringSnowflake) Value() (driver.Value, error) {
+	return strconv.FormatInt(int64(ss), 10), nil
+}
@@ -0,0 +1,37 @@
+package snowflake
+
+import "testing"
+
+func TestSnowflakeScan(t *testing.T) {
+	var sf Snowflake
+	if err := sf.Scan([]byte("123456789")); err != nil || sf != 123456789 {
+		t.Errorf("(1) Scan of []byte returned %d, %v!", sf, err)
+	}
+
+	if err := sf.Scan(int64(987654321)); err != nil || sf != 987654321 {
+		t.Errorf("(2) Scan of int64 returned %d, %v!", sf, err)
+	}
+
+	if err := sf.Scan([]byte("12ab")); err == nil {
+		t.Errorf("(3) Scan of malformed []byte did not fail!")
+	}
+
+	if err := sf.Scan(nil); err == nil {
+		t.Errorf("(4) Scan of NULL did not fail!")
+	}
+}
+
+func TestStringSnowflakeValue(t *testing.T) {
+	sf := Snowflake(2856524282194824821)
+	if v, err := sf.Value(); err != nil || v != int64(2856524282194824821) {
+		t.Errorf("(1) Snowflake value is %#v, %v, expected an int64!", v, err)
+	}
+	if v, err := StringSnowflake(sf).Value(); err != nil || v != "2856524282194824821" {
+		t.Errorf("(2) StringSnowflake value is %#v, %v, expected a string!", v, err)
+	}
+
+	var ss StringSnowflake
+	if err := ss.Scan("2856524282194824821"); err != nil || Snowflake(ss) != sf {
+		t.Errorf("(3) Scan returned %d, %v!", ss, err)
+	}
+}
@@ -0,0 +1,93 @@
+package snowflake
+
+import (
+	"log"
+	"time"
+)
+
+// Restoring a snapshot older than this logs a warning, since IDs may
+// have been generated after it was taken.
+const staleSnapshotAge = time.Minute
+
+// SnowflakeNodeState is the generation state of a node: the timestamp
+// field and sequence number of the last ID it issued.
+type SnowflakeNodeState struct {
+	Time       int64
+	Sequence   int64
+	CapturedAt time.Time
+}
+
+// Snapshot returns the node's current generation state, e.g. to persist
+// across restarts. It is safe to call concurrently with Next.
+func (self *SnowflakeNode) Snapshot() SnowflakeNodeState {
+	self.mutex.Lock()
+	state := SnowflakeNodeState{
+		Time:     self.time,
+		Sequence: self.sequence,
+	}
+	self.mutex.Unlock()
+	state.CapturedAt = time.Now()
+	return state
+}
+
+// CurrentSequence returns the sequence number of the last ID the node
+// issued, without issuing one. It is meant for monitoring and debugging:
+// the value is a snapshot that may be stale as soon as it is returned.
+func (self *SnowflakeNode) CurrentSequence() int64 {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	return self.sequence
+}
+
+// CurrentTime returns the timestamp field of the last ID the node issued,
+// in time units (milliseconds unless set by WithTimeUnit) since its
+// epoch. Like CurrentSequence it is a snapshot for monitoring only.
+func (self *SnowflakeNode) CurrentTime() int64 {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	return self.time
+}
+
+// SequenceUtilization returns the fraction of the current millisecond's
+// sequence space the node has used, from 0 to 1, where 1 means it has
+// run out and is waiting for the clock. Like CurrentSequence it is a
+// snapshot for monitoring; for secure nodes it is not meaningful.
+func (self *SnowflakeNode) SequenceUtilization() float64 {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	if self.time == 0 || self.time < self.millis() {
+		return 0
+	}
+	return float64(self.sequence+1) / float64(self.seqStep+1)
+}
+
+// Restore moves the node forward to state, so that it will not reissue
+// IDs up to and including the one state records. It never moves the node
+// backwards. Since the node cannot know which earlier milliseconds were
+// used, NextWithTimestamp refuses to back-date up to state's. A warning
+// is logged if state is stale.
+func (self *SnowflakeNode) Restore(state SnowflakeNodeState) {
+	if age := time.Since(state.CapturedAt); age > staleSnapshotAge {
+		log.Printf("snowflake: restoring node %d state captured %v ago", self.nodeId, age.Round(time.Second))
+	}
+
+	self.mutex.Lock()
+	if state.Time > self.time || (state.Time == self.time && state.Sequence > self.sequence) {
+		self.time = state.Time
+		self.sequence = state.Sequence
+	}
+	self.mutex.Unlock()
+}
+
+// Reset returns the node to its initial state, as if newly constructed.
+// It is meant for tests that cannot replace an injected node: in
+// production it may cause duplicate IDs, particularly if called while
+// Next is in flight or after the clock has been set back.
+func (self *SnowflakeNode) Reset() {
+	self.mutex.Lock()
+	self.time = 0
+	self.sequence = 0
+	self.liveSince = 0
+	self.backdated = nil
+	self.mutex.Unlock()
+}
@@ -0,0 +1,85 @@
+package snowflake
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	node := NewSnowflakeNode(4)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			node.Next()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			node.Snapshot()
+		}
+	}()
+	wg.Wait()
+
+	last := node.Next()
+	state := node.Snapshot()
+	if state.Time != last.RawMillis() || state.Sequence != int64(last)&0xfff {
+		t.Errorf("(1) Snapshot %+v does not match last ID %d!", state, last)
+	}
+
+	restored := NewSnowflakeNode(4)
+	restored.Restore(state)
+	if sf := restored.Next(); sf <= last {
+		t.Errorf("(2) Restored node issued %d, not after %d!", sf, last)
+	}
+}
+
+func TestCurrentSequence(t *testing.T) {
+	node := NewSnowflakeNode(4)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			node.Next()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			node.CurrentSequence()
+			node.CurrentTime()
+		}
+	}()
+	wg.Wait()
+
+	last := node.Next()
+	if seq := node.CurrentSequence(); seq != int64(last)&0xfff {
+		t.Errorf("(1) Current sequence %d does not match last ID %d!", seq, last)
+	}
+	if ms := node.CurrentTime(); ms != last.RawMillis() {
+		t.Errorf("(2) Current time %d does not match last ID %d!", ms, last)
+	}
+	if node.CurrentSequence() != node.CurrentSequence() {
+		t.Errorf("(3) Reading the sequence changed it!")
+	}
+}
+
+func TestReset(t *testing.T) {
+	node := NewSnowflakeNode(4)
+	for i := 0; i < 100; i++ {
+		node.Next()
+	}
+
+	node.Reset()
+	if state := node.Snapshot(); state.Time != 0 || state.Sequence != 0 {
+		t.Errorf("(1) Reset left state %+v!", state)
+	}
+	if _, _, seq := node.Next().parts(); seq != 0 {
+		t.Errorf("(2) First ID after Reset has sequence %d!", seq)
+	}
+}
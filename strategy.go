@@ -0,0 +1,122 @@
+package snowflake
+
+import (
+	"runtime"
+	"time"
+)
+
+// SpinMode is how a node waits between clock reads while waiting for
+// the clock to move on.
+type SpinMode int
+
+const (
+	// SpinYield, the default, yields the processor with runtime.Gosched
+	// between reads. On an idle machine it notices the clock about as
+	// quickly as SpinBusy, but lets other goroutines run meanwhile.
+	SpinYield SpinMode = iota
+	// SpinBusy re-reads the clock straight away. It reacts soonest but
+	// keeps a CPU busy for up to a millisecond per wait.
+	SpinBusy
+	// SpinSleep sleeps briefly between reads, using the least CPU at the
+	// cost of up to the scheduler's sleep granularity in latency.
+	SpinSleep
+)
+
+// How long SpinSleep sleeps between clock reads.
+const spinSleepDuration = 50 * time.Microsecond
+
+// pause waits between two clock reads as the node's SpinStrategy says.
+func (self *SnowflakeNode) pause() {
+	self.SpinStrategy.pause()
+}
+
+// pause waits between two clock reads in mode m.
+func (m SpinMode) pause() {
+	switch m {
+	case SpinBusy:
+	case SpinSleep:
+		time.Sleep(spinSleepDuration)
+	default:
+		runtime.Gosched()
+	}
+}
+
+// A SequenceExhaustionStrategy decides what a node does when it has
+// issued every sequence number for the current millisecond. Blocking
+// generation calls OnExhausted with the clock reading, in milliseconds
+// since the node's epoch, and reads the clock again if it returns nil;
+// an error is returned from NextE instead. OnExhausted runs with the
+// node locked, so it must not call the node's methods.
+type SequenceExhaustionStrategy interface {
+	OnExhausted(node *SnowflakeNode, currentTime int64) error
+}
+
+// WithExhaustionStrategy makes the node wait out sequence exhaustion
+// using s rather than by polling the clock. Any WithRetryOnExhaustion
+// bound still applies.
+func WithExhaustionStrategy(s SequenceExhaustionStrategy) Option {
+	return func(node *SnowflakeNode) error {
+		node.strategy = s
+		return nil
+	}
+}
+
+// WithOnExhaustion calls fn each time the node has had to wait for the
+// clock because its sequence space for the current millisecond was used
+// up, with how long it waited, e.g. to log exhaustion as it happens
+// rather than polling Stats. fn is called once per wait, after the
+// node's lock is released, so it may call the node's methods; calls
+// that do not wait never call it.
+func WithOnExhaustion(fn func(n *SnowflakeNode, elapsed time.Duration)) Option {
+	return func(node *SnowflakeNode) error {
+		node.onExhaustion = fn
+		return nil
+	}
+}
+
+// takeWaits returns the exhaustion waits recorded for onExhaustion since
+// the last call. Must be called with the mutex held.
+func (self *SnowflakeNode) takeWaits() []time.Duration {
+	waits := self.waits
+	self.waits = nil
+	return waits
+}
+
+// reportWaits passes waits taken by takeWaits to onExhaustion. Must be
+// called without the mutex held.
+func (self *SnowflakeNode) reportWaits(waits []time.Duration) {
+	for _, d := range waits {
+		self.onExhaustion(self, d)
+	}
+}
+
+// SpinStrategy waits between clock reads as the node's SpinStrategy
+// field says, as nodes do by default.
+type SpinStrategy struct{}
+
+func (SpinStrategy) OnExhausted(node *SnowflakeNode, _ int64) error {
+	node.pause()
+	return nil
+}
+
+// SleepStrategy sleeps for Duration, or a millisecond if it is zero,
+// between clock reads; this trades latency for CPU.
+type SleepStrategy struct {
+	Duration time.Duration
+}
+
+func (s SleepStrategy) OnExhausted(*SnowflakeNode, int64) error {
+	d := s.Duration
+	if d <= 0 {
+		d = time.Millisecond
+	}
+	time.Sleep(d)
+	return nil
+}
+
+// ErrorStrategy fails with ErrSequenceExhausted instead of waiting.
+type ErrorStrategy struct{}
+
+func (ErrorStrategy) OnExhausted(*SnowflakeNode, int64) error {
+	return ErrSequenceExhausted
+}
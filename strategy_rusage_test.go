@@ -0,0 +1,39 @@
+//go:build linux || darwin
+
+package snowflake
+
+import (
+	"syscall"
+	"testing"
+	"time"
+)
+
+func cpuTime(b *testing.B) time.Duration {
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err != nil {
+		b.Fatalf("getrusage: %v", err)
+	}
+	return time.Duration(ru.Utime.Nano() + ru.Stime.Nano())
+}
+
+// BenchmarkSpinStrategy generates IDs at the maximum rate, so most calls
+// that wait do so for the sequence to wrap, and reports the CPU time
+// used per ID next to the wall time.
+func BenchmarkSpinStrategy(b *testing.B) {
+	for _, c := range []struct {
+		name string
+		mode SpinMode
+	}{{"busy", SpinBusy}, {"yield", SpinYield}, {"sleep", SpinSleep}} {
+		b.Run(c.name, func(b *testing.B) {
+			node := NewSnowflakeNode(1)
+			node.SpinStrategy = c.mode
+			start := cpuTime(b)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				node.Next()
+			}
+			b.StopTimer()
+			b.ReportMetric(float64(cpuTime(b)-start)/float64(b.N), "cpu-ns/op")
+		})
+	}
+}
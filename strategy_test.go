@@ -0,0 +1,152 @@
+package snowflake
+
+import (
+	"testing"
+	"time"
+)
+
+// thawingStrategy wraps a strategy and moves the frozen clock forward a
+// millisecond after it has been consulted calls times.
+type thawingStrategy struct {
+	SequenceExhaustionStrategy
+	now   *time.Time
+	calls int
+	seen  int
+}
+
+func (s *thawingStrategy) OnExhausted(node *SnowflakeNode, currentTime int64) error {
+	s.seen++
+	if s.seen == s.calls {
+		*s.now = s.now.Add(time.Millisecond)
+	}
+	return s.SequenceExhaustionStrategy.OnExhausted(node, currentTime)
+}
+
+func exhaust(t *testing.T, node *SnowflakeNode) Snowflake {
+	var last Snowflake
+	for i := 0; i < 4096; i++ {
+		sf, err := node.NextE()
+		if err != nil {
+			t.Fatalf("ID %d failed with %v!", i, err)
+		}
+		last = sf
+	}
+	return last
+}
+
+func TestSpinStrategy(t *testing.T) {
+	frozen := time.Now()
+	s := &thawingStrategy{SequenceExhaustionStrategy: SpinStrategy{}, now: &frozen, calls: 3}
+	node, _ := New(1,
+		WithClock(func() time.Time { return frozen }),
+		WithExhaustionStrategy(s))
+
+	last := exhaust(t, node)
+	sf, err := node.NextE()
+	if err != nil {
+		t.Fatalf("(1) Next failed with %v!", err)
+	}
+	if s.seen != 3 || sf.RawMillis() != last.RawMillis()+1 {
+		t.Errorf("(2) Expected 3 spins into the next millisecond, got %d spins and %d after %d!", s.seen, sf, last)
+	}
+}
+
+func TestSleepStrategy(t *testing.T) {
+	frozen := time.Now()
+	d := 2 * time.Millisecond
+	s := &thawingStrategy{SequenceExhaustionStrategy: SleepStrategy{Duration: d}, now: &frozen, calls: 2}
+	node, _ := New(1,
+		WithClock(func() time.Time { return frozen }),
+		WithExhaustionStrategy(s))
+
+	last := exhaust(t, node)
+	start := time.Now()
+	sf, err := node.NextE()
+	if err != nil {
+		t.Fatalf("(1) Next failed with %v!", err)
+	}
+	if elapsed := time.Since(start); elapsed < 2*d {
+		t.Errorf("(2) Expected to sleep at least %v, took %v!", 2*d, elapsed)
+	}
+	if sf.RawMillis() != last.RawMillis()+1 {
+		t.Errorf("(3) ID %d is not in the millisecond after %d!", sf, last)
+	}
+}
+
+func TestErrorStrategy(t *testing.T) {
+	frozen := time.Now()
+	node, _ := New(1,
+		WithClock(func() time.Time { return frozen }),
+		WithExhaustionStrategy(ErrorStrategy{}))
+
+	exhaust(t, node)
+	if _, err := node.NextE(); err != ErrSequenceExhausted {
+		t.Errorf("(1) Expected ErrSequenceExhausted, got %v!", err)
+	}
+	if sf := node.Next(); sf != -1 {
+		t.Errorf("(2) Expected -1 from Next, got %d!", sf)
+	}
+
+	frozen = frozen.Add(time.Millisecond)
+	if _, err := node.NextE(); err != nil {
+		t.Errorf("(3) Node did not recover in the next millisecond: %v", err)
+	}
+}
+
+func TestSpinModes(t *testing.T) {
+	for _, mode := range []SpinMode{SpinYield, SpinBusy, SpinSleep} {
+		node := NewSnowflakeNode(1)
+		node.SpinStrategy = mode
+		last := node.Next()
+		for i := 0; i < 3*4096; i++ {
+			sf := node.Next()
+			if sf <= last {
+				t.Fatalf("(1) ID %d after %d with spin mode %d is not increasing!", sf, last, mode)
+			}
+			last = sf
+		}
+	}
+	if node := NewSnowflakeNode(1); node.SpinStrategy != SpinYield {
+		t.Errorf("(2) Default spin mode is %d, expected SpinYield!", node.SpinStrategy)
+	}
+}
+
+// steppingStrategy moves the frozen clock forward a millisecond every
+// time it is consulted.
+type steppingStrategy struct {
+	now *time.Time
+}
+
+func (s steppingStrategy) OnExhausted(*SnowflakeNode, int64) error {
+	*s.now = s.now.Add(time.Millisecond)
+	return nil
+}
+
+func TestWithOnExhaustion(t *testing.T) {
+	frozen := time.Now()
+	var waits []time.Duration
+	var node *SnowflakeNode
+	node, _ = New(1,
+		WithClock(func() time.Time { return frozen }),
+		WithExhaustionStrategy(steppingStrategy{now: &frozen}),
+		WithOnExhaustion(func(n *SnowflakeNode, elapsed time.Duration) {
+			// Called without the lock, so the node may be used
+			n.CurrentSequence()
+			waits = append(waits, elapsed)
+		}))
+
+	exhaust(t, node)
+	if len(waits) != 0 {
+		t.Errorf("(1) Callback fired %d times during normal generation!", len(waits))
+	}
+	node.Next()
+	if len(waits) != 1 || waits[0] < 0 {
+		t.Errorf("(2) Expected one exhaustion, got %v!", waits)
+	}
+	if ids := node.NextN(2 * 4096); len(ids) != 2*4096 || len(waits) != 3 {
+		t.Errorf("(3) Expected 3 exhaustions after two more milliseconds, got %d!", len(waits))
+	}
+	if _, _, err := node.ReserveBlock(4096); err != nil || len(waits) != 4 {
+		t.Errorf("(4) Expected ReserveBlock to exhaust once, got %v and %d!", err, len(waits))
+	}
+}
@@ -0,0 +1,144 @@
+package snowflake
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// The smallest and largest valid snowflakes.
+const (
+	MinSnowflake Snowflake = 0
+	MaxSnowflake Snowflake = math.MaxInt64
+)
+
+// MinForTime returns the smallest ID a default node could generate at or
+// after t, for use as an inclusive lower bound in range queries. Times
+// before the epoch give MinSnowflake and times past the end of the
+// timestamp range give MaxSnowflake.
+func MinForTime(t time.Time) Snowflake {
+	if t.Before(time.UnixMilli(baseEpoch)) {
+		return MinSnowflake
+	}
+	// Check the range before converting: UnixMilli overflows for times
+	// far enough ahead
+	if t.After(time.UnixMilli(baseEpoch + 1<<DefaultLayout.TimestampBits - 1)) {
+		return MaxSnowflake
+	}
+	return Snowflake((ceilMillis(t) - baseEpoch) << baseTimeShift)
+}
+
+// ceilMillis returns the Unix time of t in milliseconds, rounded up to a
+// whole millisecond. Unlike UnixNano it does not overflow after 2262.
+func ceilMillis(t time.Time) int64 {
+	ms := t.UnixMilli()
+	if t.Nanosecond()%1000000 != 0 {
+		ms++
+	}
+	return ms
+}
+
+// DayStart returns the smallest ID generated on the UTC calendar day of
+// t, so a day's IDs satisfy DayStart(d) <= id < DayStart(d.AddDate(0, 0, 1)).
+// Days before the epoch give MinSnowflake.
+func DayStart(t time.Time) Snowflake {
+	y, m, d := t.UTC().Date()
+	return MinForTime(time.Date(y, m, d, 0, 0, 0, 0, time.UTC))
+}
+
+// MonthRange returns the smallest and largest IDs a default node could
+// generate in the given UTC calendar month, so the month's IDs satisfy
+// min <= id <= max, e.g. to prune monthly partitions. A month starting
+// before the epoch has min clamped to MinSnowflake; one that ends before
+// the epoch has no IDs and gives an empty range, with max below min.
+// Months past the end of the timestamp range give MaxSnowflake.
+func MonthRange(year int, month time.Month) (min, max Snowflake) {
+	start := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	min = MinForTime(start)
+	next := MinForTime(start.AddDate(0, 1, 0))
+	if next == MaxSnowflake {
+		return min, MaxSnowflake
+	}
+	return min, next - 1
+}
+
+// WeekBucket returns the number of whole weeks between the default epoch
+// and the time sf was generated, e.g. to route IDs to time-based storage
+// shards. Weeks are 7 days of UTC time counted from the epoch itself
+// (Thursday 2021-01-21 18:00 UTC), not calendar weeks.
+func (sf Snowflake) WeekBucket() int64 {
+	return sf.RawMillis() / int64(7*24*time.Hour/time.Millisecond)
+}
+
+// WindowIndex returns the index of the window of the given length, counted
+// from the default epoch, that sf was generated in, i.e.
+// sf.RawMillis() / window.Milliseconds(), e.g. as a key for counting
+// events per rate-limit window without reading the clock. Windows are
+// whole milliseconds, since that is all an ID records: fractions are
+// truncated, and windows shorter than a millisecond (or not positive)
+// count as one, so the index is RawMillis.
+func (sf Snowflake) WindowIndex(window time.Duration) int64 {
+	ms := window.Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+	return sf.RawMillis() / ms
+}
+
+// SnowflakesInWindow returns the IDs in ids whose embedded time, relative
+// to the epoch epochMs (in Unix milliseconds), falls within [start, end].
+// The input is checked for order in one O(n) pass: sorted input is then
+// binary searched and copied, while unsorted input is scanned twice, to
+// size the result and to fill it. The result is a new slice in the order
+// of ids.
+func SnowflakesInWindow(start, end time.Time, ids []Snowflake, epochMs int64) []Snowflake {
+	// Round start up and end down to whole milliseconds, since that is
+	// all an ID records.
+	lo := ceilMillis(start) - epochMs
+	hi := end.UnixMilli() - epochMs
+	if lo > hi {
+		return []Snowflake{}
+	}
+	in := func(sf Snowflake) bool {
+		ms := sf.RawMillis()
+		return ms >= lo && ms <= hi
+	}
+
+	if sort.SliceIsSorted(ids, func(a, b int) bool { return ids[a] < ids[b] }) {
+		first := sort.Search(len(ids), func(i int) bool { return ids[i].RawMillis() >= lo })
+		last := sort.Search(len(ids), func(i int) bool { return ids[i].RawMillis() > hi })
+		out := make([]Snowflake, last-first)
+		copy(out, ids[first:last])
+		return out
+	}
+
+	n := 0
+	for _, sf := range ids {
+		if in(sf) {
+			n++
+		}
+	}
+	out := make([]Snowflake, 0, n)
+	for _, sf := range ids {
+		if in(sf) {
+			out = append(out, sf)
+		}
+	}
+	return out
+}
+
+// EstimatedRate returns the rate, in IDs per second, at which the
+// time-sorted ids were generated: the number of intervals between them
+// divided by the time from the first to the last. Fewer than two IDs
+// give 0. If they all share a millisecond the rate cannot be measured
+// and EstimatedRate returns +Inf.
+func EstimatedRate(ids []Snowflake) float64 {
+	if len(ids) < 2 {
+		return 0
+	}
+	span := ids[len(ids)-1].RawMillis() - ids[0].RawMillis()
+	if span <= 0 {
+		return math.Inf(1)
+	}
+	return float64(len(ids)-1) / (float64(span) / 1000)
+}
@@ -0,0 +1,186 @@
+package snowflake
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestSnowflakesInWindow(t *testing.T) {
+	base := time.UnixMilli(baseEpoch)
+	ids := []Snowflake{1 << 22, 2<<22 | 7, 3 << 22, 4<<22 | 1<<12, 5 << 22}
+
+	got := SnowflakesInWindow(base.Add(2*time.Millisecond), base.Add(4*time.Millisecond), ids, baseEpoch)
+	if len(got) != 3 || got[0] != ids[1] || got[2] != ids[3] {
+		t.Errorf("(1) Sorted window returned %v!", got)
+	}
+
+	shuffled := []Snowflake{ids[4], ids[1], ids[0], ids[3], ids[2]}
+	got = SnowflakesInWindow(base.Add(2*time.Millisecond), base.Add(4*time.Millisecond), shuffled, baseEpoch)
+	if len(got) != 3 || cap(got) != 3 || got[0] != ids[1] || got[1] != ids[3] {
+		t.Errorf("(2) Unsorted window returned %v with capacity %d!", got, cap(got))
+	}
+
+	got = SnowflakesInWindow(base.Add(1500*time.Microsecond), base.Add(1900*time.Microsecond), ids, baseEpoch)
+	if len(got) != 0 {
+		t.Errorf("(3) Sub-millisecond window returned %v!", got)
+	}
+
+	// UnixNano overflows after 2262
+	far := time.Date(2400, 1, 1, 0, 0, 0, 0, time.UTC)
+	if got := SnowflakesInWindow(far, far.AddDate(100, 0, 0), ids, baseEpoch); len(got) != 0 {
+		t.Errorf("(4) Window from 2400 to 2500 returned %v!", got)
+	}
+}
+
+func benchmarkWindowIDs(sorted bool) []Snowflake {
+	ids := make([]Snowflake, 1000000)
+	for i := range ids {
+		ids[i] = Snowflake(int64(i/100)<<22 | int64(i%100))
+	}
+	if !sorted {
+		rand.Shuffle(len(ids), func(a, b int) { ids[a], ids[b] = ids[b], ids[a] })
+	}
+	return ids
+}
+
+func BenchmarkSnowflakesInWindowSorted(b *testing.B) {
+	ids := benchmarkWindowIDs(true)
+	start, end := time.UnixMilli(baseEpoch+2000), time.UnixMilli(baseEpoch+3000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		SnowflakesInWindow(start, end, ids, baseEpoch)
+	}
+}
+
+func BenchmarkSnowflakesInWindowUnsorted(b *testing.B) {
+	ids := benchmarkWindowIDs(false)
+	start, end := time.UnixMilli(baseEpoch+2000), time.UnixMilli(baseEpoch+3000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		SnowflakesInWindow(start, end, ids, baseEpoch)
+	}
+}
+
+func TestWeekBucket(t *testing.T) {
+	epoch := time.UnixMilli(baseEpoch)
+	week := 7 * 24 * time.Hour
+	cases := []struct {
+		t      time.Time
+		bucket int64
+	}{
+		{epoch, 0},
+		{epoch.Add(week - time.Millisecond), 0},
+		{epoch.Add(week), 1},
+		{epoch.Add(100*week + time.Hour), 100},
+	}
+	for i, c := range cases {
+		sf, _ := Encode(c.t, 1, 1)
+		if b := sf.WeekBucket(); b != c.bucket {
+			t.Errorf("(%d) ID from %v is in week %d, expected %d!", i+1, c.t, b, c.bucket)
+		}
+	}
+}
+
+func TestWindowIndex(t *testing.T) {
+	sf := Snowflake(125999<<22 | 5<<12 | 7)
+	cases := []struct {
+		window time.Duration
+		index  int64
+	}{
+		{time.Minute, 2},
+		{time.Second, 125},
+		{1500 * time.Microsecond, 125999},
+		{time.Millisecond, 125999},
+		{time.Microsecond, 125999},
+		{0, 125999},
+	}
+	for i, c := range cases {
+		if idx := sf.WindowIndex(c.window); idx != c.index {
+			t.Errorf("(%d) Window %v gave index %d, expected %d!", i+1, c.window, idx, c.index)
+		}
+	}
+}
+
+func TestMinForTime(t *testing.T) {
+	epoch := time.UnixMilli(baseEpoch)
+	if sf := MinForTime(epoch.Add(1500 * time.Microsecond)); sf != 2<<baseTimeShift {
+		t.Errorf("(1) Expected 1.5ms to round up to the 2ms bound, got %d!", sf)
+	}
+	if sf := MinForTime(epoch.Add(2 * time.Millisecond)); sf != 2<<baseTimeShift {
+		t.Errorf("(2) Whole millisecond was rounded, got %d!", sf)
+	}
+	last := time.UnixMilli(baseEpoch + 1<<41 - 1)
+	if sf := MinForTime(last); sf != Snowflake((1<<41-1)<<baseTimeShift) {
+		t.Errorf("(3) Last millisecond of the range gave %d!", sf)
+	}
+	if sf := MinForTime(last.Add(time.Microsecond)); sf != MaxSnowflake {
+		t.Errorf("(4) Time past the range gave %d!", sf)
+	}
+	// UnixNano overflows after 2262
+	for _, year := range []int{2263, 2500} {
+		if sf := MinForTime(time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)); sf != MaxSnowflake {
+			t.Errorf("(5) %d gave %d, expected MaxSnowflake!", year, sf)
+		}
+	}
+}
+
+func TestDayStart(t *testing.T) {
+	day := time.Date(2023, 5, 17, 13, 45, 0, 0, time.UTC)
+	start := DayStart(day)
+	if _, node, seq := start.parts(); !start.Time().Equal(time.Date(2023, 5, 17, 0, 0, 0, 0, time.UTC)) || node != 0 || seq != 0 {
+		t.Errorf("(1) DayStart(%v) returned %d at %v!", day, start, start.Time())
+	}
+
+	late, _ := Encode(time.Date(2023, 5, 17, 23, 59, 59, 0, time.UTC), 1023, 4095)
+	if late < start || late >= DayStart(day.AddDate(0, 0, 1)) {
+		t.Errorf("(2) ID %d from 23:59:59 is outside the day's range!", late)
+	}
+
+	if sf := DayStart(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)); sf != MinSnowflake {
+		t.Errorf("(3) Day before the epoch returned %d!", sf)
+	}
+}
+
+func TestMonthRange(t *testing.T) {
+	min, max := MonthRange(2023, time.May)
+	if min != DayStart(time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)) || max != DayStart(time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC))-1 {
+		t.Errorf("(1) May 2023 gave [%d, %d]!", min, max)
+	}
+	late, _ := Encode(time.Date(2023, 5, 31, 23, 59, 59, 999000000, time.UTC), 1023, 4095)
+	if late != max {
+		t.Errorf("(2) Expected the last ID of May to be %d, got %d!", late, max)
+	}
+
+	// January 2021 straddles the epoch, 2021-01-21T18:00Z
+	min, max = MonthRange(2021, time.January)
+	if min != MinSnowflake || max != MinForTime(time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC))-1 {
+		t.Errorf("(3) January 2021 gave [%d, %d]!", min, max)
+	}
+	if min, max := MonthRange(2020, time.December); min != MinSnowflake || max >= min {
+		t.Errorf("(4) Month before the epoch gave non-empty range [%d, %d]!", min, max)
+	}
+	if min, max := MonthRange(2200, time.January); min != MaxSnowflake || max != MaxSnowflake {
+		t.Errorf("(5) Month past the timestamp range gave [%d, %d]!", min, max)
+	}
+	if min, max := MonthRange(2500, time.January); min != MaxSnowflake || max != MaxSnowflake {
+		t.Errorf("(6) Month after 2262 gave [%d, %d]!", min, max)
+	}
+}
+
+func TestEstimatedRate(t *testing.T) {
+	var ids []Snowflake
+	for ms := int64(0); ms <= 1000; ms += 10 {
+		ids = append(ids, Snowflake(ms<<22|5<<12))
+	}
+	if r := EstimatedRate(ids); r != 100 {
+		t.Errorf("(1) Expected 100 IDs per second, got %v!", r)
+	}
+	if r := EstimatedRate([]Snowflake{1 << 22, 1<<22 | 1}); !math.IsInf(r, 1) {
+		t.Errorf("(2) Expected +Inf for one millisecond, got %v!", r)
+	}
+	if r := EstimatedRate(ids[:1]); r != 0 {
+		t.Errorf("(3) Expected 0 for a single ID, got %v!", r)
+	}
+}
@@ -0,0 +1,36 @@
+package snowflake
+
+import "encoding/binary"
+
+// 100ns intervals between the UUID epoch, 1582-10-15, and the Unix epoch.
+const uuidEpochOffset = 0x01B21DD213814000
+
+// SnowflakeToUint128 embeds sf in the low half of a 128-bit value, with
+// high in the high half, e.g. for protocols with 128-bit identifiers.
+func SnowflakeToUint128(sf Snowflake, high uint64) [2]uint64 {
+	return [2]uint64{high, uint64(sf)}
+}
+
+// Uint128ToSnowflake returns the snowflake embedded in the low half of v
+// by SnowflakeToUint128.
+func Uint128ToSnowflake(v [2]uint64) Snowflake {
+	return Snowflake(v[1])
+}
+
+// ToTimeUUID returns sf as a version 1 (time-based) UUID, e.g. for
+// Cassandra timeuuid columns: the time fields hold the generation time,
+// assuming the default epoch, the clock sequence holds the sequence field
+// and the node field holds the node ID. Distinct IDs give distinct UUIDs,
+// and the UUID time orders them to the millisecond.
+func ToTimeUUID(sf Snowflake) [16]byte {
+	ms, node, seq := sf.parts()
+	ts := uint64(baseEpoch+ms)*10000 + uuidEpochOffset
+
+	var u [16]byte
+	binary.BigEndian.PutUint32(u[0:], uint32(ts))
+	binary.BigEndian.PutUint16(u[4:], uint16(ts>>32))
+	binary.BigEndian.PutUint16(u[6:], uint16(ts>>48)&0x0fff|0x1000)
+	binary.BigEndian.PutUint16(u[8:], uint16(seq)&0x3fff|0x8000)
+	binary.BigEndian.PutUint16(u[14:], uint16(node))
+	return u
+}
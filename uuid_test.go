@@ -0,0 +1,28 @@
+package snowflake
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSnowflakeToUint128(t *testing.T) {
+	sf := Snowflake(2856524282194824821)
+	v := SnowflakeToUint128(sf, 0xdeadbeef)
+	if v[0] != 0xdeadbeef || Uint128ToSnowflake(v) != sf {
+		t.Errorf("(1) %d packed as %x did not round trip!", sf, v)
+	}
+}
+
+func TestToTimeUUID(t *testing.T) {
+	// 2021-01-21T18:00:01Z, node 5, sequence 3
+	sf := Snowflake(1000<<22 | 5<<12 | 3)
+	u := ToTimeUUID(sf)
+	got := fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:])
+	if want := "7b232680-5c12-11eb-8003-000000000005"; got != want {
+		t.Errorf("(1) UUID for %d is %s, expected %s!", sf, got, want)
+	}
+
+	if ToTimeUUID(sf) == ToTimeUUID(sf+1) {
+		t.Errorf("(2) Consecutive IDs gave the same UUID!")
+	}
+}
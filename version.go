@@ -0,0 +1,37 @@
+package snowflake
+
+import "errors"
+
+// Width of the version field of IDs from versioned nodes, taken from the
+// top of the node field.
+const versionBits = 4
+
+// Width of what remains of the node field for the shard ID.
+const versionedNodeBits = baseNodeBits - versionBits
+
+// ErrVersionOutOfRange is returned when a version does not fit the
+// version field.
+var ErrVersionOutOfRange = errors.New("snowflake: version out of range")
+
+// NewVersionedNode returns a node that tags every ID with version,
+// readable back with Version, so decoders can tell layouts apart. The
+// version takes the top 4 bits of the default node field, leaving 6 bits
+// (shard IDs 0 to 63) for shardId; the timestamp and sequence fields are
+// untouched, so the IDs decode like any other. Node and NodeID report
+// the whole node field, version included.
+func NewVersionedNode(shardId, version int) (*SnowflakeNode, error) {
+	if version < 0 || version >= 1<<versionBits {
+		return nil, ErrVersionOutOfRange
+	}
+	if shardId < 0 || shardId >= 1<<versionedNodeBits {
+		return nil, ErrNodeIDOutOfRange
+	}
+	return NewSnowflakeNodeWithBits(version<<versionedNodeBits|shardId, baseNodeBits, baseSeqIdBits)
+}
+
+// Version returns the version field of sf, as set by a node from
+// NewVersionedNode. For IDs from other nodes it reads the top of the
+// node ID and has no meaning.
+func (sf Snowflake) Version() int {
+	return int(sf.Node() >> versionedNodeBits)
+}
@@ -0,0 +1,32 @@
+package snowflake
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewVersionedNode(t *testing.T) {
+	node, err := NewVersionedNode(5, 3)
+	if err != nil {
+		t.Fatalf("(1) Could not create node: %v", err)
+	}
+	sf, err := node.NextE()
+	if err != nil {
+		t.Fatalf("(2) Could not generate ID: %v", err)
+	}
+	if sf.Version() != 3 || sf.Node()&(1<<versionedNodeBits-1) != 5 || !node.Owns(sf) {
+		t.Errorf("(3) ID %d does not carry version 3 and shard 5!", sf)
+	}
+	if age := sf.Age(); age < 0 || age > time.Second {
+		t.Errorf("(4) Versioned ID has age %v!", age)
+	}
+
+	for _, v := range []int{-1, 16} {
+		if _, err := NewVersionedNode(5, v); err != ErrVersionOutOfRange {
+			t.Errorf("(5) Version %d was not rejected, got %v!", v, err)
+		}
+	}
+	if _, err := NewVersionedNode(64, 1); err != ErrNodeIDOutOfRange {
+		t.Errorf("(6) Shard ID too wide for 6 bits was not rejected, got %v!", err)
+	}
+}